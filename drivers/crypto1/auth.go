@@ -0,0 +1,87 @@
+package crypto1
+
+import "errors"
+
+// ErrAuthentication is returned if the peer answers with a wrong nonce
+// successor, in most cases because it uses another key.
+var ErrAuthentication = errors.New("crypto1: authentication failed")
+
+// The three pass authentication, see [1] 2.1:
+//
+//	card   -> reader: nT
+//	reader -> card:   {nR} {aR = suc64(nT)}
+//	card   -> reader: {aT = suc96(nT)}
+//
+// The words in braces are encrypted. Both sides start the cipher with the
+// key and shift in the UID xor nT.
+
+// Reader is the reader side of the authentication.
+type Reader struct {
+	cipher *Cipher
+	nt     uint32
+}
+
+// NewReader starts the authentication of the reader with the card nonce nT.
+func NewReader(key [6]byte, uid uint32, nt uint32) *Reader {
+	c := New(key)
+	c.Word(uid^nt, false)
+	return &Reader{cipher: c, nt: nt}
+}
+
+// Answer returns the encrypted reader nonce and the encrypted answer to the
+// card nonce.
+func (r *Reader) Answer(nr uint32) (nrEnc uint32, arEnc uint32) {
+	nrEnc = nr ^ r.cipher.Word(nr, false)
+	arEnc = Successor(r.nt, 64) ^ r.cipher.Word(0, false)
+	return nrEnc, arEnc
+}
+
+// Verify checks the encrypted answer of the card. On success the cipher
+// encrypts the following frames.
+func (r *Reader) Verify(atEnc uint32) (*Cipher, error) {
+	if atEnc^r.cipher.Word(0, false) != Successor(r.nt, 96) {
+		return nil, ErrAuthentication
+	}
+	return r.cipher, nil
+}
+
+// Tag is the card side of the authentication.
+type Tag struct {
+	cipher *Cipher
+	nt     uint32
+}
+
+// NewTag starts the authentication of the card, which sends the nonce nT
+// to the reader.
+func NewTag(key [6]byte, uid uint32, nt uint32) *Tag {
+	c := New(key)
+	c.Word(uid^nt, false)
+	return &Tag{cipher: c, nt: nt}
+}
+
+// Respond checks the encrypted answer of the reader and returns the
+// encrypted answer of the card. On success the cipher encrypts the following
+// frames.
+func (t *Tag) Respond(nrEnc uint32, arEnc uint32) (atEnc uint32, cipher *Cipher, err error) {
+	t.cipher.Word(nrEnc, true)
+	if arEnc^t.cipher.Word(0, false) != Successor(t.nt, 64) {
+		return 0, nil, ErrAuthentication
+	}
+	atEnc = Successor(t.nt, 96) ^ t.cipher.Word(0, false)
+	return atEnc, t.cipher, nil
+}
+
+// VerifyTrace checks a recorded authentication against the key. It returns
+// a cipher positioned after the authentication, ready to decrypt the
+// following frames of the trace.
+func VerifyTrace(key [6]byte, uid, nt, nrEnc, arEnc, atEnc uint32) (*Cipher, error) {
+	tag := NewTag(key, uid, nt)
+	at, cipher, err := tag.Respond(nrEnc, arEnc)
+	if err != nil {
+		return nil, err
+	}
+	if at != atEnc {
+		return nil, ErrAuthentication
+	}
+	return cipher, nil
+}
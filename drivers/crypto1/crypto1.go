@@ -0,0 +1,136 @@
+// Package crypto1 implements the proprietary Crypto1 stream cipher of the
+// MIFARE Classic family and its three pass mutual authentication. The PN532
+// runs the cipher itself, this package allows to emulate a card or to check
+// recorded traces on the host.
+//
+// The state is kept split into the odd and even bits of the 48 bit LFSR, as
+// described in [1].
+//
+// [1] Garcia et al., Dismantling MIFARE Classic, ESORICS 2008
+package crypto1
+
+// Feedback taps of the LFSR, split into odd and even bits
+const (
+	polyOdd  = 0x29CE5C
+	polyEven = 0x870804
+)
+
+// Cipher is the state of the Crypto1 stream cipher.
+type Cipher struct {
+	odd  uint32
+	even uint32
+}
+
+// New returns a cipher loaded with the 6 byte sector key.
+func New(key [6]byte) *Cipher {
+	var k uint64
+	for _, b := range key {
+		k = k<<8 | uint64(b)
+	}
+	c := &Cipher{}
+	for i := 47; i > 0; i -= 2 {
+		c.odd = c.odd<<1 | uint32(k>>((i-1)^7)&1)
+		c.even = c.even<<1 | uint32(k>>(i^7)&1)
+	}
+	return c
+}
+
+// filter is the nonlinear output function applied to the odd state bits.
+func filter(x uint32) uint32 {
+	f := uint32(0xf22c0) >> (x & 0xf) & 16
+	f |= uint32(0x6c9c0) >> (x >> 4 & 0xf) & 8
+	f |= uint32(0x3c8b0) >> (x >> 8 & 0xf) & 4
+	f |= uint32(0x1e458) >> (x >> 12 & 0xf) & 2
+	f |= uint32(0x0d938) >> (x >> 16 & 0xf) & 1
+	return 0xEC57E80A >> f & 1
+}
+
+// parity returns the even parity of x.
+func parity(x uint32) uint32 {
+	x ^= x >> 16
+	x ^= x >> 8
+	x ^= x >> 4
+	return 0x6996 >> (x & 0xf) & 1
+}
+
+// Bit returns the next keystream bit and shifts in the input bit. With
+// encrypted set, the input is the ciphertext of a bit the keystream is mixed
+// into, like the reader nonce during the authentication.
+func (c *Cipher) Bit(in uint8, encrypted bool) uint8 {
+	ks := filter(c.odd)
+	feed := uint32(in & 1)
+	if encrypted {
+		feed ^= ks
+	}
+	feed ^= polyOdd&c.odd ^ polyEven&c.even
+	c.even = c.even<<1 | parity(feed)
+	c.odd, c.even = c.even, c.odd
+	return uint8(ks)
+}
+
+// Byte returns the next 8 keystream bits, LSB first as transmitted, and
+// shifts in the input byte.
+func (c *Cipher) Byte(in byte, encrypted bool) byte {
+	var ks byte
+	for i := 0; i < 8; i++ {
+		ks |= c.Bit(in>>i, encrypted) << i
+	}
+	return ks
+}
+
+// Word returns the next 32 keystream bits and shifts in the input. Words are
+// transmitted MSB first, so the first byte sent holds the upper 8 bits.
+func (c *Cipher) Word(in uint32, encrypted bool) uint32 {
+	var ks uint32
+	for i := 0; i < 32; i++ {
+		bit := i ^ 24 // big endian byte order, LSB first within a byte
+		ks |= uint32(c.Bit(uint8(in>>bit), encrypted)) << bit
+	}
+	return ks
+}
+
+// peek returns the next keystream bit without clocking the cipher. It
+// encrypts the parity bit following each byte.
+func (c *Cipher) peek() uint8 {
+	return uint8(filter(c.odd))
+}
+
+// Encrypt encrypts the frame in place and returns the encrypted odd parity
+// bit of every byte, as sent with the frame by the card and the reader.
+func (c *Cipher) Encrypt(frame []byte) []uint8 {
+	parities := make([]uint8, len(frame))
+	for i, b := range frame {
+		frame[i] = b ^ c.Byte(0, false)
+		parities[i] = oddParity(b) ^ c.peek()
+	}
+	return parities
+}
+
+// Decrypt decrypts the frame in place. Decryption and encryption are the same
+// outside of the authentication.
+func (c *Cipher) Decrypt(frame []byte) {
+	for i := range frame {
+		frame[i] ^= c.Byte(0, false)
+	}
+}
+
+// oddParity returns the ISO 14443-A parity bit of the byte.
+func oddParity(b byte) uint8 {
+	return uint8(parity(uint32(b)) ^ 1)
+}
+
+// Successor returns the nonce n steps after x of the 16 bit LFSR generating
+// the card nonces. The reader answers the card nonce with its successor 64,
+// the card answers with the successor 96.
+func Successor(x uint32, n int) uint32 {
+	x = swapEndian(x)
+	for ; n > 0; n-- {
+		x = x>>1 | (x>>16^x>>18^x>>19^x>>21)<<31
+	}
+	return swapEndian(x)
+}
+
+func swapEndian(x uint32) uint32 {
+	x = x>>8&0x00ff00ff | x&0x00ff00ff<<8
+	return x>>16 | x<<16
+}
@@ -0,0 +1,125 @@
+package crypto1
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Authentications recorded with a Proxmark, published as examples of the
+// mfkey64 tool of the Proxmark3 repository.
+var traces = []struct {
+	name                         string
+	key                          [6]byte
+	uid, nt, nrEnc, arEnc, atEnc uint32
+	// the first frame the reader sent after the authentication
+	frameEnc, frame []byte
+}{
+	{
+		name:  "mfkey64 default key",
+		key:   [6]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+		uid:   0x9C599B32,
+		nt:    0x82A4166C,
+		nrEnc: 0xA1E458CE,
+		arEnc: 0x6EEA41E0,
+		atEnc: 0x5CADF439,
+	},
+	{
+		name:     "mfkey64 with data",
+		key:      [6]byte{0x09, 0x1E, 0x63, 0x9C, 0xB7, 0x15},
+		uid:      0x14579F69,
+		nt:       0xCE844261,
+		nrEnc:    0xF8049CCB,
+		arEnc:    0x0525C84F,
+		atEnc:    0x9431CC40,
+		frameEnc: []byte{0x70, 0x93, 0xDF, 0x99},
+		frame:    []byte{0x30, 0x14, 0xA7, 0xFE}, // READ block 0x14 with CRC
+	},
+}
+
+func TestVerifyTrace(t *testing.T) {
+	for _, trace := range traces {
+		t.Run(trace.name, func(t *testing.T) {
+			cipher, err := VerifyTrace(trace.key, trace.uid, trace.nt, trace.nrEnc, trace.arEnc, trace.atEnc)
+			if err != nil {
+				t.Fatalf("VerifyTrace: %v", err)
+			}
+			if trace.frameEnc == nil {
+				return
+			}
+			frame := append([]byte{}, trace.frameEnc...)
+			cipher.Decrypt(frame)
+			if !bytes.Equal(frame, trace.frame) {
+				t.Errorf("decrypted frame %X, want %X", frame, trace.frame)
+			}
+		})
+	}
+}
+
+func TestVerifyTraceWrongKey(t *testing.T) {
+	trace := traces[0]
+	key := [6]byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}
+	if _, err := VerifyTrace(key, trace.uid, trace.nt, trace.nrEnc, trace.arEnc, trace.atEnc); err != ErrAuthentication {
+		t.Errorf("VerifyTrace with wrong key: %v, want ErrAuthentication", err)
+	}
+	if _, err := VerifyTrace(trace.key, trace.uid, trace.nt, trace.nrEnc, trace.arEnc, trace.atEnc^1); err != ErrAuthentication {
+		t.Errorf("VerifyTrace with wrong card answer: %v, want ErrAuthentication", err)
+	}
+}
+
+// The reader half of an authentication, published as example of the mfkey32
+// tool of the Proxmark3 repository.
+func TestReaderAnswerTrace(t *testing.T) {
+	const uid, nt, nrEnc, arEnc = 0x12345678, 0x1AD8DF2B, 0x1D316024, 0x620EF048
+	cipher := New([6]byte{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5})
+	cipher.Word(uid^nt, false)
+	cipher.Word(nrEnc, true)
+	if ar := arEnc ^ cipher.Word(0, false); ar != Successor(nt, 64) {
+		t.Errorf("reader answer %08X, want %08X", ar, Successor(nt, 64))
+	}
+}
+
+func TestReaderTag(t *testing.T) {
+	key := [6]byte{0x4D, 0x3A, 0x99, 0xC3, 0x51, 0xDD}
+	const uid, nt, nr = 0xDEADBEEF, 0x01200145, 0x12345678
+	reader := NewReader(key, uid, nt)
+	tag := NewTag(key, uid, nt)
+	nrEnc, arEnc := reader.Answer(nr)
+	atEnc, tagCipher, err := tag.Respond(nrEnc, arEnc)
+	if err != nil {
+		t.Fatalf("Respond: %v", err)
+	}
+	readerCipher, err := reader.Verify(atEnc)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := VerifyTrace(key, uid, nt, nrEnc, arEnc, atEnc); err != nil {
+		t.Errorf("VerifyTrace of the own authentication: %v", err)
+	}
+	frame := []byte{0x30, 0x04, 0x26, 0xEE}
+	encrypted := append([]byte{}, frame...)
+	readerCipher.Encrypt(encrypted)
+	tagCipher.Decrypt(encrypted)
+	if !bytes.Equal(encrypted, frame) {
+		t.Errorf("the tag decrypted %X, want %X", encrypted, frame)
+	}
+}
+
+func TestTagWrongKey(t *testing.T) {
+	const uid, nt = 0xDEADBEEF, 0x01200145
+	reader := NewReader([6]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, uid, nt)
+	tag := NewTag([6]byte{}, uid, nt)
+	nrEnc, arEnc := reader.Answer(0x12345678)
+	if _, _, err := tag.Respond(nrEnc, arEnc); err != ErrAuthentication {
+		t.Errorf("Respond: %v, want ErrAuthentication", err)
+	}
+}
+
+func TestSuccessor(t *testing.T) {
+	const nt = 0x82A4166C
+	if got := Successor(nt, 0); got != nt {
+		t.Errorf("Successor(nt, 0) = %08X, want %08X", got, nt)
+	}
+	if a, b := Successor(nt, 96), Successor(Successor(nt, 64), 32); a != b {
+		t.Errorf("Successor(nt, 96) = %08X, Successor(Successor(nt, 64), 32) = %08X", a, b)
+	}
+}
@@ -0,0 +1,213 @@
+package llcp
+
+// Data link connection states
+const (
+	stateConnecting = iota
+	stateConnected
+	stateDisconnecting
+	stateClosed
+)
+
+// The receive window announced for our connections
+const receiveWindow = 1
+
+// Conn is a connection-oriented data link connection. Every Write is split
+// into I PDUs of at most the remote MIU, every Read returns the information
+// of a single I PDU.
+type Conn struct {
+	link       *Link
+	local      uint8 // Local SAP
+	remote     uint8 // Remote SAP
+	state      int
+	err        error
+	vs         uint8 // Send state variable V(S)
+	vsa        uint8 // Send acknowledgement state variable V(SA)
+	vr         uint8 // Receive state variable V(R)
+	remoteRW   uint8
+	remoteMIU  uint16
+	remoteBusy bool
+	ackPending bool
+	sendQueue  [][]byte
+	recvQueue  [][]byte
+}
+
+func (l *Link) newConn(local, remote uint8) *Conn {
+	c := &Conn{
+		link:      l,
+		local:     local,
+		remote:    remote,
+		remoteRW:  1,
+		remoteMIU: DefaultMIU,
+	}
+	l.conns = append(l.conns, c)
+	return c
+}
+
+// MIU returns the maximum amount of data the peer accepts in a single I PDU.
+func (c *Conn) MIU() int {
+	return int(c.remoteMIU)
+}
+
+// Write queues the data and runs the link until the peer has acknowledged
+// all of it.
+func (c *Conn) Write(data []byte) (int, error) {
+	if c.state != stateConnected {
+		return 0, ErrNotConnected
+	}
+	for rest := data; len(rest) > 0; {
+		n := len(rest)
+		if n > int(c.remoteMIU) {
+			n = int(c.remoteMIU)
+		}
+		c.sendQueue = append(c.sendQueue, append([]byte{}, rest[:n]...))
+		rest = rest[n:]
+	}
+	for len(c.sendQueue) > 0 || c.vsa != c.vs {
+		if c.state != stateConnected {
+			return 0, c.err
+		}
+		if err := c.link.Step(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Read runs the link until the peer has sent information and copies it into
+// buffer. Information not fitting into buffer is returned by the next Read.
+// It returns io.EOF once the peer has disconnected.
+func (c *Conn) Read(buffer []byte) (int, error) {
+	for len(c.recvQueue) == 0 {
+		if c.state != stateConnected {
+			return 0, c.err
+		}
+		if err := c.link.Step(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(buffer, c.recvQueue[0])
+	if n < len(c.recvQueue[0]) {
+		c.recvQueue[0] = c.recvQueue[0][n:]
+	} else {
+		c.recvQueue = c.recvQueue[1:]
+	}
+	return n, nil
+}
+
+// Close sends a DISC and runs the link until the peer has confirmed it.
+func (c *Conn) Close() error {
+	if c.state != stateConnected {
+		return nil
+	}
+	// deliver what is still pending, the peer might wait for an acknowledgement
+	for c.state == stateConnected && (c.ackPending || len(c.sendQueue) > 0) {
+		if err := c.link.Step(); err != nil {
+			return err
+		}
+	}
+	if c.state != stateConnected {
+		return nil
+	}
+	c.state = stateDisconnecting
+	c.link.queue(PDU{DSAP: c.remote, PType: PTypeDISC, SSAP: c.local})
+	for c.state == stateDisconnecting {
+		if err := c.link.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parameters returns the RW and MIUX parameters sent in CONNECT and CC.
+func (c *Conn) parameters() []byte {
+	params := []byte{ParamRW, 1, receiveWindow}
+	if miu := c.link.local.MIU; miu > DefaultMIU {
+		miux := miu - DefaultMIU
+		params = append(params, ParamMIUX, 2, byte(miux>>8)&0x07, byte(miux))
+	}
+	return params
+}
+
+func (c *Conn) applyParameters(buffer []byte) {
+	parseParameters(buffer, func(typ uint8, value []byte) error {
+		switch typ {
+		case ParamRW:
+			c.remoteRW = value[0] & 0x0F
+		case ParamMIUX:
+			c.remoteMIU = DefaultMIU + (uint16(value[0])<<8|uint16(value[1]))&0x7FF
+		}
+		return nil
+	})
+}
+
+// canSend reports whether an I PDU may be sent within the remote receive
+// window.
+func (c *Conn) canSend() bool {
+	return c.state == stateConnected && len(c.sendQueue) > 0 && !c.remoteBusy &&
+		(c.vs-c.vsa)&0x0F < c.remoteRW
+}
+
+// nextPDU returns the next I PDU or a RR acknowledging the received ones.
+func (c *Conn) nextPDU() PDU {
+	c.ackPending = false
+	if c.canSend() {
+		pdu := PDU{
+			DSAP:        c.remote,
+			PType:       PTypeI,
+			SSAP:        c.local,
+			Sequence:    c.vs<<4 | c.vr,
+			Information: c.sendQueue[0],
+		}
+		c.sendQueue = c.sendQueue[1:]
+		c.vs = (c.vs + 1) & 0x0F
+		return pdu
+	}
+	return PDU{DSAP: c.remote, PType: PTypeRR, SSAP: c.local, Sequence: c.vr}
+}
+
+func (c *Conn) handle(pdu *PDU) {
+	switch pdu.PType {
+	case PTypeCC:
+		if c.state == stateConnecting {
+			c.remote = pdu.SSAP
+			c.applyParameters(pdu.Information)
+			c.state = stateConnected
+		}
+	case PTypeDM:
+		if c.state == stateConnecting {
+			c.err = ErrRejected
+			if len(pdu.Information) > 0 && pdu.Information[0] == DMNoService {
+				c.err = ErrNoService
+			}
+		} else {
+			c.err = ErrNotConnected
+		}
+		c.state = stateClosed
+	case PTypeFRMR:
+		c.err = ErrInvalidPDU
+		c.state = stateClosed
+	case PTypeI:
+		if pdu.Sequence>>4 != c.vr {
+			// invalid N(S), reject the frame and give up the connection
+			c.err = ErrInvalidPDU
+			c.state = stateClosed
+			c.link.queue(PDU{
+				DSAP:        c.remote,
+				PType:       PTypeFRMR,
+				SSAP:        c.local,
+				Information: []byte{0x10 | pdu.PType, pdu.Sequence, c.vs<<4 | c.vr, c.vsa<<4 | c.vr},
+			})
+			return
+		}
+		c.vr = (c.vr + 1) & 0x0F
+		c.recvQueue = append(c.recvQueue, append([]byte{}, pdu.Information...))
+		c.ackPending = true
+		c.vsa = pdu.Sequence & 0x0F
+	case PTypeRR:
+		c.vsa = pdu.Sequence & 0x0F
+		c.remoteBusy = false
+	case PTypeRNR:
+		c.vsa = pdu.Sequence & 0x0F
+		c.remoteBusy = true
+	}
+}
@@ -0,0 +1,432 @@
+package llcp
+
+import "io"
+
+// Transport is the NFC-DEP link LLCP runs on. The link is half-duplex: the
+// initiator sends a PDU and then receives the answer of the target, while the
+// target receives a PDU first and then sends its answer.
+type Transport interface {
+	Send(pdu []byte) error
+	Receive(buffer []byte) (int, error)
+}
+
+// service is a SAP bound by Listen or Bind.
+type service struct {
+	sap       uint8
+	name      string
+	listening bool
+	accept    []*Conn    // Connections waiting for Accept
+	datagram  []datagram // UI PDUs waiting for ReceiveFrom
+}
+
+type datagram struct {
+	source uint8
+	data   []byte
+}
+
+// Link is an activated LLCP link. The link is driven by the caller: every
+// blocking call runs Step until it is done, which exchanges exactly one PDU
+// in each direction.
+type Link struct {
+	transport Transport
+	initiator bool
+	local     Parameters
+	remote    Parameters
+	version   uint8
+	outgoing  []PDU
+	services  []*service
+	conns     []*Conn
+	closed    bool
+	rx        []byte
+	tx        []byte
+}
+
+// NewLink activates the LLCP link after the DEP activation. The local
+// parameters must match the general bytes sent in the ATR, remoteGeneralBytes
+// are the general bytes received from the peer.
+func NewLink(transport Transport, initiator bool, local Parameters, remoteGeneralBytes []byte) (*Link, error) {
+	remote, err := ParseGeneralBytes(remoteGeneralBytes)
+	if err != nil {
+		return nil, err
+	}
+	version, err := agreeVersion(local.Version, remote.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &Link{
+		transport: transport,
+		initiator: initiator,
+		local:     local,
+		remote:    remote,
+		version:   version,
+		rx:        make([]byte, int(local.MIU)+3),
+		tx:        make([]byte, 0, int(remote.MIU)+3),
+	}, nil
+}
+
+// Remote returns the parameters announced by the peer.
+func (l *Link) Remote() Parameters {
+	return l.remote
+}
+
+// Version returns the agreed LLCP version.
+func (l *Link) Version() uint8 {
+	return l.version
+}
+
+// Step exchanges a single PDU in each direction. A SYMM PDU is sent when
+// there is nothing else to send.
+func (l *Link) Step() error {
+	if l.closed {
+		return ErrLinkClosed
+	}
+	if l.initiator {
+		if err := l.send(); err != nil {
+			return err
+		}
+		return l.receive()
+	}
+	if err := l.receive(); err != nil {
+		return err
+	}
+	return l.send()
+}
+
+// Close deactivates the link by sending a DISC to the link management SAP.
+func (l *Link) Close() error {
+	if l.closed {
+		return nil
+	}
+	l.outgoing = append(l.outgoing[:0], PDU{PType: PTypeDISC})
+	var err error
+	if l.initiator {
+		err = l.send()
+	} else {
+		if err = l.receive(); err == nil {
+			err = l.send()
+		}
+	}
+	l.closed = true
+	return err
+}
+
+// Bind reserves a SAP for connectionless transport.
+func (l *Link) Bind(sap uint8) error {
+	if sap >= sapCount || l.service(sap) != nil {
+		return ErrNoSAP
+	}
+	l.services = append(l.services, &service{sap: sap})
+	return nil
+}
+
+// SendTo queues an UI PDU from the bound source SAP to the destination SAP.
+// It is sent with the next Step.
+func (l *Link) SendTo(dsap uint8, ssap uint8, data []byte) error {
+	if len(data) > int(l.remote.MIU) {
+		return ErrTooLarge
+	}
+	if l.service(ssap) == nil {
+		return ErrNoSAP
+	}
+	l.queue(PDU{DSAP: dsap, PType: PTypeUI, SSAP: ssap, Information: append([]byte{}, data...)})
+	return nil
+}
+
+// ReceiveFrom runs the link until an UI PDU arrives on the bound SAP and
+// copies it into buffer. It returns the size of the data and the source SAP.
+func (l *Link) ReceiveFrom(sap uint8, buffer []byte) (int, uint8, error) {
+	s := l.service(sap)
+	if s == nil {
+		return 0, 0, ErrNoSAP
+	}
+	for len(s.datagram) == 0 {
+		if err := l.Step(); err != nil {
+			return 0, 0, err
+		}
+	}
+	d := s.datagram[0]
+	s.datagram = s.datagram[1:]
+	return copy(buffer, d.data), d.source, nil
+}
+
+// Listen offers a connection-oriented service on the SAP. Peers connect to it
+// either by the SAP or by the service name.
+func (l *Link) Listen(sap uint8, name string) error {
+	if err := l.Bind(sap); err != nil {
+		return err
+	}
+	s := l.service(sap)
+	s.name = name
+	s.listening = true
+	return nil
+}
+
+// Accept runs the link until a peer has connected to the service listening
+// on the SAP.
+func (l *Link) Accept(sap uint8) (*Conn, error) {
+	s := l.service(sap)
+	if s == nil {
+		return nil, ErrNoSAP
+	}
+	for len(s.accept) == 0 {
+		if err := l.Step(); err != nil {
+			return nil, err
+		}
+	}
+	c := s.accept[0]
+	s.accept = s.accept[1:]
+	return c, nil
+}
+
+// Connect establishes a data link connection to the service with the given
+// name, like "urn:nfc:sn:snep".
+func (l *Link) Connect(name string) (*Conn, error) {
+	params := append([]byte{ParamSN, byte(len(name))}, name...)
+	return l.connect(SAPServiceDiscovery, params)
+}
+
+// ConnectSAP establishes a data link connection to the service bound to the
+// destination SAP.
+func (l *Link) ConnectSAP(dsap uint8) (*Conn, error) {
+	return l.connect(dsap, nil)
+}
+
+func (l *Link) connect(dsap uint8, params []byte) (*Conn, error) {
+	ssap, ok := l.freeSAP()
+	if !ok {
+		return nil, ErrNoSAP
+	}
+	c := l.newConn(ssap, dsap)
+	c.state = stateConnecting
+	l.queue(PDU{DSAP: dsap, PType: PTypeCONNECT, SSAP: ssap, Information: append(c.parameters(), params...)})
+	for c.state == stateConnecting {
+		if err := l.Step(); err != nil {
+			l.removeConn(c)
+			return nil, err
+		}
+	}
+	if c.state != stateConnected {
+		l.removeConn(c)
+		return nil, c.err
+	}
+	return c, nil
+}
+
+func (l *Link) queue(pdu PDU) {
+	l.outgoing = append(l.outgoing, pdu)
+}
+
+// send transmits the next pending PDU. Queued control PDUs go first, then
+// information of the connections and their acknowledgements.
+func (l *Link) send() error {
+	pdu := PDU{PType: PTypeSYMM}
+	if len(l.outgoing) > 0 {
+		pdu = l.outgoing[0]
+		l.outgoing = l.outgoing[1:]
+	} else if c := l.nextSender(); c != nil {
+		pdu = c.nextPDU()
+	}
+	l.tx = pdu.Marshal(l.tx[:0])
+	return l.transport.Send(l.tx)
+}
+
+func (l *Link) nextSender() *Conn {
+	for _, c := range l.conns {
+		if c.canSend() {
+			return c
+		}
+	}
+	for _, c := range l.conns {
+		if c.ackPending {
+			return c
+		}
+	}
+	return nil
+}
+
+func (l *Link) receive() error {
+	n, err := l.transport.Receive(l.rx)
+	if err != nil {
+		return err
+	}
+	return l.handle(l.rx[:n])
+}
+
+// handle processes a received PDU. See [1] 5.6 for the connection-oriented
+// transport.
+func (l *Link) handle(buffer []byte) error {
+	var pdu PDU
+	if err := pdu.Unmarshal(buffer); err != nil {
+		return err
+	}
+	switch pdu.PType {
+	case PTypeSYMM:
+	case PTypeAGF:
+		info := pdu.Information
+		for len(info) >= 2 {
+			length := int(info[0])<<8 | int(info[1])
+			if len(info) < 2+length {
+				return ErrInvalidPDU
+			}
+			if err := l.handle(info[2 : 2+length]); err != nil {
+				return err
+			}
+			info = info[2+length:]
+		}
+	case PTypePAX:
+		params, err := ParseGeneralBytes(append(Magic[:], pdu.Information...))
+		if err != nil {
+			return err
+		}
+		l.remote = params
+	case PTypeUI:
+		if s := l.service(pdu.DSAP); s != nil {
+			s.datagram = append(s.datagram, datagram{
+				source: pdu.SSAP,
+				data:   append([]byte{}, pdu.Information...),
+			})
+		}
+	case PTypeCONNECT:
+		l.handleConnect(&pdu)
+	case PTypeSNL:
+		if pdu.DSAP == SAPServiceDiscovery {
+			l.handleServiceLookup(&pdu)
+		}
+	case PTypeDISC:
+		if pdu.DSAP == SAPLinkManagement && pdu.SSAP == SAPLinkManagement {
+			l.closed = true
+			return ErrLinkClosed
+		}
+		reason := uint8(DMNoConnection)
+		if c := l.conn(pdu.DSAP, pdu.SSAP); c != nil {
+			c.state = stateClosed
+			c.err = io.EOF
+			l.removeConn(c)
+			reason = DMDisconnected
+		}
+		l.queue(PDU{DSAP: pdu.SSAP, PType: PTypeDM, SSAP: pdu.DSAP, Information: []byte{reason}})
+	default:
+		l.handleConnection(&pdu)
+	}
+	return nil
+}
+
+func (l *Link) handleConnect(pdu *PDU) {
+	var s *service
+	if pdu.DSAP == SAPServiceDiscovery {
+		// connect by name, the name decides which service is meant
+		parseParameters(pdu.Information, func(typ uint8, value []byte) error {
+			if typ == ParamSN {
+				s = l.serviceByName(string(value))
+			}
+			return nil
+		})
+	} else {
+		s = l.service(pdu.DSAP)
+	}
+	if s == nil || !s.listening {
+		l.queue(PDU{DSAP: pdu.SSAP, PType: PTypeDM, SSAP: pdu.DSAP, Information: []byte{DMNoService}})
+		return
+	}
+	c := l.newConn(s.sap, pdu.SSAP)
+	c.applyParameters(pdu.Information)
+	c.state = stateConnected
+	s.accept = append(s.accept, c)
+	l.queue(PDU{DSAP: pdu.SSAP, PType: PTypeCC, SSAP: s.sap, Information: c.parameters()})
+}
+
+// handleServiceLookup answers the service discovery requests of a SNL PDU
+// with the SAP bound to each name, 0 for unknown names. See [1] 4.3.10.
+func (l *Link) handleServiceLookup(pdu *PDU) {
+	var response []byte
+	parseParameters(pdu.Information, func(typ uint8, value []byte) error {
+		if typ != ParamSDREQ || len(value) < 1 {
+			return nil
+		}
+		sap := uint8(0)
+		if s := l.serviceByName(string(value[1:])); s != nil {
+			sap = s.sap
+		}
+		response = append(response, ParamSDRES, 2, value[0], sap)
+		return nil
+	})
+	if len(response) > 0 {
+		l.queue(PDU{DSAP: pdu.SSAP, PType: PTypeSNL, SSAP: SAPServiceDiscovery, Information: response})
+	}
+}
+
+func (l *Link) handleConnection(pdu *PDU) {
+	if pdu.PType == PTypeCC || pdu.PType == PTypeDM {
+		// the answer to a CONNECT by name is sent from the service SAP
+		for _, c := range l.conns {
+			if c.local == pdu.DSAP && (c.state == stateConnecting || c.state == stateDisconnecting) {
+				c.handle(pdu)
+				if c.state == stateClosed {
+					l.removeConn(c)
+				}
+				return
+			}
+		}
+	}
+	c := l.conn(pdu.DSAP, pdu.SSAP)
+	if c == nil {
+		if pdu.PType != PTypeDM {
+			l.queue(PDU{DSAP: pdu.SSAP, PType: PTypeDM, SSAP: pdu.DSAP, Information: []byte{DMNoConnection}})
+		}
+		return
+	}
+	c.handle(pdu)
+	if c.state == stateClosed {
+		l.removeConn(c)
+	}
+}
+
+func (l *Link) service(sap uint8) *service {
+	for _, s := range l.services {
+		if s.sap == sap {
+			return s
+		}
+	}
+	return nil
+}
+
+func (l *Link) serviceByName(name string) *service {
+	for _, s := range l.services {
+		if s.name != "" && s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (l *Link) conn(local, remote uint8) *Conn {
+	for _, c := range l.conns {
+		if c.local == local && c.remote == remote {
+			return c
+		}
+	}
+	return nil
+}
+
+func (l *Link) removeConn(c *Conn) {
+	for i := range l.conns {
+		if l.conns[i] == c {
+			l.conns = append(l.conns[:i], l.conns[i+1:]...)
+			return
+		}
+	}
+}
+
+// freeSAP returns an unregistered local SAP for an outgoing connection.
+func (l *Link) freeSAP() (uint8, bool) {
+	for sap := uint8(sapFirstUnregistered); sap < sapCount; sap++ {
+		used := l.service(sap) != nil
+		for _, c := range l.conns {
+			used = used || c.local == sap
+		}
+		if !used {
+			return sap, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,236 @@
+// Package llcp provides a minimal implementation of the NFC Forum Logical
+// Link Control Protocol on top of a NFC-DEP link, as provided by the PN532
+// driver.
+//
+// [1] NFC Forum Logical Link Control Protocol Technical Specification 1.1
+package llcp
+
+import "errors"
+
+// Magic number prefixing the LLCP parameters in the ATR general bytes
+var Magic = [...]byte{0x46, 0x66, 0x6D}
+
+// PDU types. See [1] 4.3.
+const (
+	PTypeSYMM    = 0x0 // Symmetry
+	PTypePAX     = 0x1 // Parameter exchange
+	PTypeAGF     = 0x2 // Aggregated frame
+	PTypeUI      = 0x3 // Unnumbered information
+	PTypeCONNECT = 0x4 // Connect
+	PTypeDISC    = 0x5 // Disconnect
+	PTypeCC      = 0x6 // Connection complete
+	PTypeDM      = 0x7 // Disconnected mode
+	PTypeFRMR    = 0x8 // Frame reject
+	PTypeSNL     = 0x9 // Service name lookup
+	PTypeI       = 0xC // Information
+	PTypeRR      = 0xD // Receive ready
+	PTypeRNR     = 0xE // Receive not ready
+)
+
+// Parameter types. See [1] 4.5.
+const (
+	ParamVersion = 0x01
+	ParamMIUX    = 0x02
+	ParamWKS     = 0x03
+	ParamLTO     = 0x04
+	ParamRW      = 0x05
+	ParamSN      = 0x06
+	ParamOPT     = 0x07
+	ParamSDREQ   = 0x08 // Service discovery request, TID and name
+	ParamSDRES   = 0x09 // Service discovery response, TID and SAP
+)
+
+// Well known service access points
+const (
+	SAPLinkManagement   = 0x00
+	SAPServiceDiscovery = 0x01 // urn:nfc:sn:sdp
+	SAPSNEP             = 0x04 // urn:nfc:sn:snep
+	// Local SAPs which are not advertised by the service discovery
+	sapFirstUnregistered = 0x20
+	sapCount             = 0x40
+)
+
+// Disconnected mode reasons. See [1] 4.3.8.
+const (
+	DMDisconnected = 0x00 // Acknowledges a DISC
+	DMNoConnection = 0x01 // No active connection for the connection-oriented PDU
+	DMNoService    = 0x02 // No service bound to the target SAP
+	DMRejected     = 0x03 // The CONNECT has been rejected by the service
+)
+
+const (
+	// Version 1.1 of LLCP
+	Version = 0x11
+	// The default maximum information unit
+	DefaultMIU = 128
+	// The default link timeout in milliseconds
+	DefaultLTO = 100
+)
+
+var (
+	ErrInvalidMagic = errors.New("llcp: missing LLCP magic number")
+	ErrInvalidPDU   = errors.New("llcp: invalid PDU")
+	ErrInvalidParam = errors.New("llcp: invalid parameter")
+	ErrVersion      = errors.New("llcp: incompatible LLCP version")
+	ErrLinkClosed   = errors.New("llcp: link deactivated")
+	ErrRejected     = errors.New("llcp: connection rejected")
+	ErrNoService    = errors.New("llcp: service not available")
+	ErrNotConnected = errors.New("llcp: not connected")
+	ErrTooLarge     = errors.New("llcp: information exceeds the MIU")
+	ErrNoSAP        = errors.New("llcp: no free service access point")
+)
+
+// Parameters are exchanged during the link activation in the ATR general
+// bytes or later in a PAX PDU.
+type Parameters struct {
+	Version uint8  // Major version in the upper, minor version in the lower nibble
+	MIU     uint16 // Maximum information unit, at least DefaultMIU
+	WKS     uint16 // Bit mask of the well known services offered
+	LTO     uint16 // Link timeout in milliseconds
+	Options uint8  // Link service class
+}
+
+// DefaultParameters returns the parameters announced by this implementation.
+func DefaultParameters() Parameters {
+	return Parameters{
+		Version: Version,
+		MIU:     DefaultMIU,
+		WKS:     1<<SAPLinkManagement | 1<<SAPServiceDiscovery,
+		LTO:     DefaultLTO,
+		Options: 0x03, // Connectionless and connection-oriented transport
+	}
+}
+
+// GeneralBytes returns the magic number followed by the parameters, as sent
+// in the ATR_REQ or ATR_RES.
+func GeneralBytes(params *Parameters) []byte {
+	buffer := append([]byte{}, Magic[:]...)
+	return appendParameters(buffer, params)
+}
+
+// ParseGeneralBytes checks the magic number and decodes the parameters of
+// the ATR_REQ or ATR_RES general bytes.
+func ParseGeneralBytes(buffer []byte) (Parameters, error) {
+	if len(buffer) < len(Magic) || string(buffer[:len(Magic)]) != string(Magic[:]) {
+		return Parameters{}, ErrInvalidMagic
+	}
+	params := Parameters{MIU: DefaultMIU, LTO: DefaultLTO, WKS: 1 << SAPLinkManagement}
+	err := parseParameters(buffer[len(Magic):], func(typ uint8, value []byte) error {
+		switch typ {
+		case ParamVersion:
+			params.Version = value[0]
+		case ParamMIUX:
+			params.MIU = DefaultMIU + (uint16(value[0])<<8|uint16(value[1]))&0x7FF
+		case ParamWKS:
+			params.WKS = uint16(value[0])<<8 | uint16(value[1])
+		case ParamLTO:
+			params.LTO = uint16(value[0]) * 10
+		case ParamOPT:
+			params.Options = value[0]
+		}
+		return nil
+	})
+	if err != nil {
+		return params, err
+	}
+	if params.Version == 0 {
+		return params, ErrInvalidParam
+	}
+	return params, nil
+}
+
+func appendParameters(buffer []byte, params *Parameters) []byte {
+	buffer = append(buffer, ParamVersion, 1, params.Version)
+	if params.MIU > DefaultMIU {
+		miux := params.MIU - DefaultMIU
+		buffer = append(buffer, ParamMIUX, 2, byte(miux>>8)&0x07, byte(miux))
+	}
+	buffer = append(buffer, ParamWKS, 2, byte(params.WKS>>8), byte(params.WKS))
+	if params.LTO != DefaultLTO {
+		buffer = append(buffer, ParamLTO, 1, byte(params.LTO/10))
+	}
+	return append(buffer, ParamOPT, 1, params.Options)
+}
+
+// parseParameters calls fn for every TLV encoded parameter. The length of
+// the fixed size parameters is checked before fn is called.
+func parseParameters(buffer []byte, fn func(typ uint8, value []byte) error) error {
+	for len(buffer) > 0 {
+		if len(buffer) < 2 || len(buffer) < 2+int(buffer[1]) {
+			return ErrInvalidParam
+		}
+		typ, value := buffer[0], buffer[2:2+int(buffer[1])]
+		buffer = buffer[2+len(value):]
+		want := 0
+		switch typ {
+		case ParamVersion, ParamLTO, ParamRW, ParamOPT:
+			want = 1
+		case ParamMIUX, ParamWKS, ParamSDRES:
+			want = 2
+		}
+		if want != 0 && len(value) != want {
+			return ErrInvalidParam
+		}
+		if err := fn(typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// agreeVersion returns the version used on the link. See [1] 5.2.2.
+func agreeVersion(local, remote uint8) (uint8, error) {
+	if local>>4 != remote>>4 {
+		return 0, ErrVersion
+	}
+	if remote < local {
+		return remote, nil
+	}
+	return local, nil
+}
+
+// PDU is a decoded LLCP protocol data unit.
+type PDU struct {
+	DSAP  uint8 // Destination service access point
+	PType uint8
+	SSAP  uint8 // Source service access point
+	// Sequence holds N(S) in the upper and N(R) in the lower nibble of the
+	// I, RR and RNR PDUs
+	Sequence    uint8
+	Information []byte
+}
+
+// hasSequence reports whether the PDU type carries a sequence field.
+func hasSequence(ptype uint8) bool {
+	return ptype == PTypeI || ptype == PTypeRR || ptype == PTypeRNR
+}
+
+// Marshal appends the encoded PDU to buffer.
+func (p *PDU) Marshal(buffer []byte) []byte {
+	buffer = append(buffer, p.DSAP<<2|p.PType>>2, p.PType<<6|p.SSAP&0x3F)
+	if hasSequence(p.PType) {
+		buffer = append(buffer, p.Sequence)
+	}
+	return append(buffer, p.Information...)
+}
+
+// Unmarshal decodes a PDU, the information field aliases buffer.
+func (p *PDU) Unmarshal(buffer []byte) error {
+	if len(buffer) < 2 {
+		return ErrInvalidPDU
+	}
+	p.DSAP = buffer[0] >> 2
+	p.PType = (buffer[0]&0x03)<<2 | buffer[1]>>6
+	p.SSAP = buffer[1] & 0x3F
+	p.Sequence = 0
+	buffer = buffer[2:]
+	if hasSequence(p.PType) {
+		if len(buffer) < 1 {
+			return ErrInvalidPDU
+		}
+		p.Sequence = buffer[0]
+		buffer = buffer[1:]
+	}
+	p.Information = buffer
+	return nil
+}
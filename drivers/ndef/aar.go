@@ -0,0 +1,24 @@
+package ndef
+
+// TypeAndroidApplication is the external type of the Android Application
+// Record, which makes Android start the app of the package, or open its
+// store page if it is not installed.
+var TypeAndroidApplication = []byte("android.com:pkg")
+
+// NewAndroidApplicationRecord returns an Android Application Record for the
+// package, like "org.example.app". Android uses the first AAR of a message,
+// put it behind the records the app handles.
+func NewAndroidApplicationRecord(pkg string) Record {
+	return Record{TNF: TNFExternal, Type: append([]byte{}, TypeAndroidApplication...), Payload: []byte(pkg)}
+}
+
+// AndroidPackage returns the package of an Android Application Record.
+func (r *Record) AndroidPackage() (string, error) {
+	if !r.isType(TNFExternal, TypeAndroidApplication) {
+		return "", ErrWrongType
+	}
+	if len(r.Payload) == 0 {
+		return "", ErrInvalidPayload
+	}
+	return string(r.Payload), nil
+}
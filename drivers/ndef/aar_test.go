@@ -0,0 +1,44 @@
+package ndef
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAndroidApplicationRecord(t *testing.T) {
+	want := append([]byte{0xD4, 0x0F, 0x0F}, "android.com:pkgorg.example.app"...) // MB, ME, SR, external
+	got, err := NewMessage(NewAndroidApplicationRecord("org.example.app")).Marshal(nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Marshal = % X, want % X", got, want)
+	}
+	message, err := Parse(want)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if pkg, err := message.Records[0].AndroidPackage(); err != nil || pkg != "org.example.app" {
+		t.Errorf("AndroidPackage = %q, %v, want org.example.app", pkg, err)
+	}
+}
+
+func TestAndroidPackageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		record Record
+		want   error
+	}{
+		{"URI record", NewURIRecord("https://tinygo.org"), ErrWrongType},
+		{"other external type", Record{TNF: TNFExternal, Type: []byte("example.com:pkg"), Payload: []byte("a")}, ErrWrongType},
+		{"well-known type", Record{TNF: TNFWellKnown, Type: TypeAndroidApplication, Payload: []byte("a")}, ErrWrongType},
+		{"empty package", NewAndroidApplicationRecord(""), ErrInvalidPayload},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.record.AndroidPackage(); err != test.want {
+				t.Errorf("AndroidPackage: %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package ndef encodes and decodes NFC Data Exchange Format messages, the
+// format NFC Forum tags and peer-to-peer links carry their data in. It has no
+// dependencies beyond the standard library and builds with TinyGo.
+//
+// [1] NFC Forum NFC Data Exchange Format (NDEF) Technical Specification 1.0
+package ndef
+
+import (
+	"errors"
+	"strconv"
+)
+
+// TNF is the type name format, telling how to interpret the record type. See
+// [1] 3.2.6.
+type TNF uint8
+
+const (
+	TNFEmpty       TNF = 0x00 // No type, ID or payload
+	TNFWellKnown   TNF = 0x01 // NFC Forum well-known type, like "U" or "T"
+	TNFMedia       TNF = 0x02 // Media type as defined in RFC 2046
+	TNFAbsoluteURI TNF = 0x03 // Absolute URI as defined in RFC 3986
+	TNFExternal    TNF = 0x04 // NFC Forum external type, like "example.com:type"
+	TNFUnknown     TNF = 0x05 // Payload of unknown type
+	TNFUnchanged   TNF = 0x06 // Middle and terminating chunks of a chunked record
+	TNFReserved    TNF = 0x07
+)
+
+func (t TNF) String() string {
+	switch t {
+	case TNFEmpty:
+		return "empty"
+	case TNFWellKnown:
+		return "well-known"
+	case TNFMedia:
+		return "media"
+	case TNFAbsoluteURI:
+		return "absolute-uri"
+	case TNFExternal:
+		return "external"
+	case TNFUnknown:
+		return "unknown"
+	case TNFUnchanged:
+		return "unchanged"
+	}
+	return "reserved"
+}
+
+// Flags of the record header. See [1] 3.2.
+const (
+	flagMB      = 0x80 // Message begin
+	flagME      = 0x40 // Message end
+	flagCF      = 0x20 // Chunk flag
+	flagSR      = 0x10 // Short record, 1 byte payload length
+	flagIL      = 0x08 // ID length present
+	maskTNF     = 0x07
+	maxShortLen = 0xFF
+)
+
+// Errors reported for malformed messages, wrapped in a *ParseError when
+// decoding.
+var (
+	ErrTruncated     = errors.New("ndef: message truncated")
+	ErrEmptyMessage  = errors.New("ndef: message without records")
+	ErrMissingMB     = errors.New("ndef: first record without message begin flag")
+	ErrUnexpectedMB  = errors.New("ndef: message begin flag on a later record")
+	ErrMissingME     = errors.New("ndef: last record without message end flag")
+	ErrTrailingData  = errors.New("ndef: data after the message end")
+	ErrReservedTNF   = errors.New("ndef: reserved type name format")
+	ErrInvalidType   = errors.New("ndef: type not allowed for the type name format")
+	ErrInvalidEmpty  = errors.New("ndef: empty record with type, ID or payload")
+	ErrInvalidChunk  = errors.New("ndef: invalid chunked record")
+	ErrRecordTooLong = errors.New("ndef: record exceeds the maximum length")
+)
+
+// ParseError reports where a message is malformed.
+type ParseError struct {
+	Offset int   // Offset of the record header within the message
+	Err    error // One of the errors above
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error() + " at offset " + strconv.Itoa(e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Record is a NDEF record. Chunked records are reassembled when decoding.
+type Record struct {
+	TNF     TNF
+	Type    []byte
+	ID      []byte
+	Payload []byte
+}
+
+// validate checks the type, ID and payload against the type name format.
+// See [1] 3.3.
+func (r *Record) validate() error {
+	switch r.TNF {
+	case TNFEmpty:
+		if len(r.Type) != 0 || len(r.ID) != 0 || len(r.Payload) != 0 {
+			return ErrInvalidEmpty
+		}
+	case TNFWellKnown, TNFMedia, TNFAbsoluteURI, TNFExternal:
+		if len(r.Type) == 0 {
+			return ErrInvalidType
+		}
+	case TNFUnknown, TNFUnchanged:
+		if len(r.Type) != 0 {
+			return ErrInvalidType
+		}
+	default:
+		return ErrReservedTNF
+	}
+	if len(r.Type) > 0xFF || len(r.ID) > 0xFF || uint64(len(r.Payload)) > 0xFFFFFFFF {
+		return ErrRecordTooLong
+	}
+	return nil
+}
+
+// appendRecord appends a record header followed by type, ID and payload.
+func appendRecord(buffer []byte, flags byte, tnf TNF, typ, id, payload []byte) []byte {
+	flags |= byte(tnf) & maskTNF
+	if len(payload) <= maxShortLen {
+		flags |= flagSR
+	}
+	if len(id) > 0 {
+		flags |= flagIL
+	}
+	buffer = append(buffer, flags, byte(len(typ)))
+	if flags&flagSR != 0 {
+		buffer = append(buffer, byte(len(payload)))
+	} else {
+		n := uint32(len(payload))
+		buffer = append(buffer, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	if len(id) > 0 {
+		buffer = append(buffer, byte(len(id)))
+	}
+	buffer = append(buffer, typ...)
+	buffer = append(buffer, id...)
+	return append(buffer, payload...)
+}
+
+// Message is a NDEF message, a sequence of records.
+type Message struct {
+	Records []Record
+}
+
+// NewMessage returns a message holding the records.
+func NewMessage(records ...Record) *Message {
+	return &Message{Records: records}
+}
+
+// Marshal appends the encoded message to buffer. A message without records
+// is encoded as a single empty record, as used for an empty tag.
+func (m *Message) Marshal(buffer []byte) ([]byte, error) {
+	return m.MarshalChunked(buffer, 0)
+}
+
+// MarshalChunked appends the encoded message to buffer, splitting payloads
+// longer than chunkSize into chunked records. A chunkSize of 0 disables
+// chunking. See [1] 2.3.3.
+func (m *Message) MarshalChunked(buffer []byte, chunkSize int) ([]byte, error) {
+	if len(m.Records) == 0 {
+		return append(buffer, flagMB|flagME|flagSR|byte(TNFEmpty), 0, 0), nil
+	}
+	for i := range m.Records {
+		r := &m.Records[i]
+		if r.TNF == TNFUnchanged {
+			return nil, ErrInvalidChunk
+		}
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		var flags byte
+		if i == 0 {
+			flags |= flagMB
+		}
+		last := i == len(m.Records)-1
+		if chunkSize <= 0 || len(r.Payload) <= chunkSize {
+			if last {
+				flags |= flagME
+			}
+			buffer = appendRecord(buffer, flags, r.TNF, r.Type, r.ID, r.Payload)
+			continue
+		}
+		// the first chunk carries type and ID, the others are unchanged
+		payload := r.Payload
+		buffer = appendRecord(buffer, flags|flagCF, r.TNF, r.Type, r.ID, payload[:chunkSize])
+		payload = payload[chunkSize:]
+		for len(payload) > chunkSize {
+			buffer = appendRecord(buffer, flagCF, TNFUnchanged, nil, nil, payload[:chunkSize])
+			payload = payload[chunkSize:]
+		}
+		flags = 0
+		if last {
+			flags |= flagME
+		}
+		buffer = appendRecord(buffer, flags, TNFUnchanged, nil, nil, payload)
+	}
+	return buffer, nil
+}
+
+// header is a decoded record header.
+type header struct {
+	flags   byte
+	tnf     TNF
+	typ     []byte
+	id      []byte
+	payload []byte
+}
+
+// parseHeader decodes the record at the start of data and returns its total
+// length.
+func parseHeader(data []byte) (header, int, error) {
+	h := header{}
+	if len(data) < 3 {
+		return h, 0, ErrTruncated
+	}
+	h.flags = data[0]
+	h.tnf = TNF(data[0] & maskTNF)
+	typeLen := int(data[1])
+	offset := 2
+	var payloadLen uint64
+	if h.flags&flagSR != 0 {
+		payloadLen = uint64(data[2])
+		offset++
+	} else {
+		if len(data) < 6 {
+			return h, 0, ErrTruncated
+		}
+		payloadLen = uint64(data[2])<<24 | uint64(data[3])<<16 | uint64(data[4])<<8 | uint64(data[5])
+		offset += 4
+	}
+	idLen := 0
+	if h.flags&flagIL != 0 {
+		if len(data) < offset+1 {
+			return h, 0, ErrTruncated
+		}
+		idLen = int(data[offset])
+		offset++
+	}
+	if uint64(len(data)-offset) < uint64(typeLen)+uint64(idLen)+payloadLen {
+		return h, 0, ErrTruncated
+	}
+	h.typ = data[offset : offset+typeLen]
+	offset += typeLen
+	h.id = data[offset : offset+idLen]
+	offset += idLen
+	h.payload = data[offset : offset+int(payloadLen)]
+	return h, offset + int(payloadLen), nil
+}
+
+// Unmarshal decodes a message and validates it strictly. Chunked records are
+// reassembled. The records hold copies of the data.
+func (m *Message) Unmarshal(data []byte) error {
+	m.Records = m.Records[:0]
+	if len(data) == 0 {
+		return &ParseError{0, ErrEmptyMessage}
+	}
+	offset := 0
+	var chunked *Record // the record being reassembled
+	for {
+		h, n, err := parseHeader(data[offset:])
+		if err != nil {
+			return &ParseError{offset, err}
+		}
+		switch {
+		case offset == 0 && h.flags&flagMB == 0:
+			return &ParseError{offset, ErrMissingMB}
+		case offset != 0 && h.flags&flagMB != 0:
+			return &ParseError{offset, ErrUnexpectedMB}
+		}
+		if chunked != nil {
+			// middle or terminating chunk. See [1] 2.3.3.
+			if h.tnf != TNFUnchanged || len(h.typ) != 0 || h.flags&flagIL != 0 {
+				return &ParseError{offset, ErrInvalidChunk}
+			}
+			chunked.Payload = append(chunked.Payload, h.payload...)
+			if h.flags&flagCF == 0 {
+				chunked = nil
+			}
+		} else {
+			record := Record{
+				TNF:     h.tnf,
+				Type:    append([]byte{}, h.typ...),
+				ID:      append([]byte{}, h.id...),
+				Payload: append([]byte{}, h.payload...),
+			}
+			if h.tnf == TNFUnchanged {
+				return &ParseError{offset, ErrInvalidChunk}
+			}
+			if err := record.validate(); err != nil {
+				return &ParseError{offset, err}
+			}
+			m.Records = append(m.Records, record)
+			if h.flags&flagCF != 0 {
+				chunked = &m.Records[len(m.Records)-1]
+			}
+		}
+		if h.flags&flagME != 0 {
+			if h.flags&flagCF != 0 {
+				return &ParseError{offset, ErrInvalidChunk}
+			}
+			if offset+n != len(data) {
+				return &ParseError{offset + n, ErrTrailingData}
+			}
+			return nil
+		}
+		offset += n
+		if offset == len(data) {
+			return &ParseError{offset, ErrMissingME}
+		}
+	}
+}
+
+// Parse decodes a message, see Message.Unmarshal.
+func Parse(data []byte) (*Message, error) {
+	m := &Message{}
+	if err := m.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,203 @@
+package ndef
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+		want    []byte
+	}{
+		{
+			name:    "empty message",
+			message: NewMessage(),
+			want:    []byte{0xD0, 0x00, 0x00},
+		},
+		{
+			name: "short record",
+			message: NewMessage(Record{
+				TNF:     TNFWellKnown,
+				Type:    []byte("U"),
+				Payload: []byte("\x04tinygo.org"),
+			}),
+			want: append([]byte{0xD1, 0x01, 0x0B, 'U'}, "\x04tinygo.org"...),
+		},
+		{
+			name: "record with ID",
+			message: NewMessage(Record{
+				TNF:     TNFMedia,
+				Type:    []byte("text/plain"),
+				ID:      []byte("id"),
+				Payload: []byte("hi"),
+			}),
+			want: append([]byte{0xDA, 0x0A, 0x02, 0x02}, "text/plainidhi"...),
+		},
+		{
+			name: "two records",
+			message: NewMessage(
+				Record{TNF: TNFExternal, Type: []byte("a:b"), Payload: []byte{1}},
+				Record{TNF: TNFUnknown, Payload: []byte{2}},
+			),
+			want: []byte{0x94, 0x03, 0x01, 'a', ':', 'b', 1, 0x55, 0x00, 0x01, 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.message.Marshal(nil)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if !bytes.Equal(got, test.want) {
+				t.Errorf("Marshal = % X, want % X", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	tests := []struct {
+		name      string
+		message   *Message
+		chunkSize int
+	}{
+		{"single record", NewMessage(Record{TNF: TNFWellKnown, Type: []byte("T"), Payload: []byte("\x02enhello")}), 0},
+		{"long record", NewMessage(Record{TNF: TNFMedia, Type: []byte("application/octet-stream"), Payload: long}), 0},
+		{"several records", NewMessage(
+			Record{TNF: TNFAbsoluteURI, Type: []byte("https://tinygo.org"), ID: []byte("1")},
+			Record{TNF: TNFUnknown, Payload: []byte{0xFF}},
+			Record{TNF: TNFExternal, Type: []byte("example.com:t"), Payload: long},
+		), 0},
+		{"chunked", NewMessage(Record{TNF: TNFMedia, Type: []byte("a/b"), ID: []byte("x"), Payload: long}), 100},
+		{"chunked last record", NewMessage(
+			Record{TNF: TNFWellKnown, Type: []byte("U"), Payload: []byte{0x00, 'a'}},
+			Record{TNF: TNFMedia, Type: []byte("a/b"), Payload: long},
+		), 7},
+		{"chunk size exceeds payload", NewMessage(Record{TNF: TNFWellKnown, Type: []byte("U"), Payload: []byte{0x00, 'a'}}), 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.message.MarshalChunked(nil, test.chunkSize)
+			if err != nil {
+				t.Fatalf("MarshalChunked: %v", err)
+			}
+			got, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			// Parse always allocates type, ID and payload
+			want := NewMessage()
+			for _, r := range test.message.Records {
+				want.Records = append(want.Records, Record{
+					TNF:     r.TNF,
+					Type:    append([]byte{}, r.Type...),
+					ID:      append([]byte{}, r.ID...),
+					Payload: append([]byte{}, r.Payload...),
+				})
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalChunked(t *testing.T) {
+	message := NewMessage(Record{TNF: TNFWellKnown, Type: []byte("T"), Payload: []byte("abcde")})
+	got, err := message.MarshalChunked(nil, 2)
+	if err != nil {
+		t.Fatalf("MarshalChunked: %v", err)
+	}
+	want := []byte{
+		0xB1, 0x01, 0x02, 'T', 'a', 'b', // MB, CF, SR, well-known
+		0x36, 0x00, 0x02, 'c', 'd', // CF, SR, unchanged
+		0x56, 0x00, 0x01, 'e', // ME, SR, unchanged
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalChunked = % X, want % X", got, want)
+	}
+}
+
+func TestParseEmptyRecord(t *testing.T) {
+	message, err := Parse([]byte{0xD0, 0x00, 0x00})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(message.Records) != 1 || message.Records[0].TNF != TNFEmpty {
+		t.Errorf("Parse = %+v, want a single empty record", message)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		want   error
+		offset int
+	}{
+		{"empty data", []byte{}, ErrEmptyMessage, 0},
+		{"header truncated", []byte{0xD1, 0x01}, ErrTruncated, 0},
+		{"long length truncated", []byte{0xC1, 0x01, 0x00, 0x00}, ErrTruncated, 0},
+		{"ID length truncated", []byte{0xD9, 0x01, 0x01}, ErrTruncated, 0},
+		{"payload truncated", []byte{0xD1, 0x01, 0x05, 'U', 0x00}, ErrTruncated, 0},
+		{"missing message begin", []byte{0x51, 0x01, 0x00, 'U'}, ErrMissingMB, 0},
+		{"second message begin", []byte{0x91, 0x01, 0x00, 'U', 0xD1, 0x01, 0x00, 'U'}, ErrUnexpectedMB, 4},
+		{"missing message end", []byte{0x91, 0x01, 0x00, 'U'}, ErrMissingME, 4},
+		{"trailing data", []byte{0xD1, 0x01, 0x00, 'U', 0x00}, ErrTrailingData, 4},
+		{"reserved TNF", []byte{0xD7, 0x00, 0x00}, ErrReservedTNF, 0},
+		{"well-known without type", []byte{0xD1, 0x00, 0x00}, ErrInvalidType, 0},
+		{"unknown with type", []byte{0xD5, 0x01, 0x00, 'U'}, ErrInvalidType, 0},
+		{"empty with payload", []byte{0xD0, 0x00, 0x01, 0x00}, ErrInvalidEmpty, 0},
+		{"unchanged first record", []byte{0xD6, 0x00, 0x00}, ErrInvalidChunk, 0},
+		{"chunk with type", []byte{0xB1, 0x01, 0x01, 'T', 'a', 0x51, 0x01, 0x01, 'T', 'b'}, ErrInvalidChunk, 5},
+		{"chunk with ID", []byte{0xB1, 0x01, 0x01, 'T', 'a', 0x5E, 0x00, 0x01, 0x01, 'i', 'b'}, ErrInvalidChunk, 5},
+		{"message end on a chunk", []byte{0xF1, 0x01, 0x01, 'T', 'a'}, ErrInvalidChunk, 0},
+		{"chunk not terminated", []byte{0xB1, 0x01, 0x01, 'T', 'a', 0x36, 0x00, 0x01, 'b'}, ErrMissingME, 9},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.data)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("Parse: %v, want %v", err, test.want)
+			}
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("Parse: %T is no *ParseError", err)
+			}
+			if parseErr.Offset != test.offset {
+				t.Errorf("offset %d, want %d", parseErr.Offset, test.offset)
+			}
+		})
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		record Record
+		want   error
+	}{
+		{"reserved TNF", Record{TNF: 7}, ErrReservedTNF},
+		{"well-known without type", Record{TNF: TNFWellKnown}, ErrInvalidType},
+		{"unknown with type", Record{TNF: TNFUnknown, Type: []byte("x")}, ErrInvalidType},
+		{"empty with payload", Record{TNF: TNFEmpty, Payload: []byte{1}}, ErrInvalidEmpty},
+		{"unchanged", Record{TNF: TNFUnchanged}, ErrInvalidChunk},
+		{"type too long", Record{TNF: TNFMedia, Type: make([]byte, 256)}, ErrRecordTooLong},
+		{"ID too long", Record{TNF: TNFMedia, Type: []byte("a/b"), ID: make([]byte, 256)}, ErrRecordTooLong},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewMessage(test.record).Marshal(nil); err != test.want {
+				t.Errorf("Marshal: %v, want %v", err, test.want)
+			}
+		})
+	}
+}
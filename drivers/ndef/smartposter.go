@@ -0,0 +1,137 @@
+package ndef
+
+import "strings"
+
+// Local types of the records nested in a Smart Poster. See NFC Forum Smart
+// Poster Record Type Definition 1.0 3.
+var (
+	typeSmartPosterAction = []byte("act")
+	typeSmartPosterSize   = []byte("s")
+	typeSmartPosterType   = []byte("t")
+)
+
+// SmartPosterAction recommends what to do with the URI of a Smart Poster.
+type SmartPosterAction uint8
+
+const (
+	ActionNone SmartPosterAction = iota // No action record
+	ActionDo                            // Open the URI, like a browser does
+	ActionSave                          // Save the URI for later
+	ActionEdit                          // Open the URI for editing
+)
+
+// Icon is an image or video shown with a Smart Poster.
+type Icon struct {
+	MIME string // The media type, starting with "image/" or "video/"
+	Data []byte
+}
+
+// SmartPoster is the content of a Smart Poster record: a URI with titles in
+// several languages, an action and icons.
+type SmartPoster struct {
+	URI    string
+	Titles []Text
+	Action SmartPosterAction
+	Size   uint32 // The size of the referenced content, 0 if unknown
+	Type   string // The media type of the referenced content, empty if unknown
+	Icons  []Icon
+}
+
+// NewSmartPosterRecord returns a Smart Poster record holding the nested URI,
+// title, action, size, type and icon records.
+func NewSmartPosterRecord(poster *SmartPoster) (Record, error) {
+	message := &Message{}
+	message.Records = append(message.Records, NewURIRecord(poster.URI))
+	for i := range poster.Titles {
+		title, err := NewTextRecordEncoded(&poster.Titles[i])
+		if err != nil {
+			return Record{}, err
+		}
+		message.Records = append(message.Records, title)
+	}
+	if poster.Action != ActionNone {
+		message.Records = append(message.Records, Record{
+			TNF:     TNFWellKnown,
+			Type:    append([]byte{}, typeSmartPosterAction...),
+			Payload: []byte{byte(poster.Action - ActionDo)},
+		})
+	}
+	if poster.Size != 0 {
+		size := poster.Size
+		message.Records = append(message.Records, Record{
+			TNF:     TNFWellKnown,
+			Type:    append([]byte{}, typeSmartPosterSize...),
+			Payload: []byte{byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size)},
+		})
+	}
+	if poster.Type != "" {
+		message.Records = append(message.Records, Record{
+			TNF:     TNFWellKnown,
+			Type:    append([]byte{}, typeSmartPosterType...),
+			Payload: []byte(poster.Type),
+		})
+	}
+	for _, icon := range poster.Icons {
+		if !strings.HasPrefix(icon.MIME, "image/") && !strings.HasPrefix(icon.MIME, "video/") {
+			return Record{}, ErrInvalidPayload
+		}
+		message.Records = append(message.Records, Record{TNF: TNFMedia, Type: []byte(icon.MIME), Payload: icon.Data})
+	}
+	payload, err := message.Marshal(nil)
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{TNF: TNFWellKnown, Type: append([]byte{}, TypeSmartPoster...), Payload: payload}, nil
+}
+
+// SmartPoster decodes a Smart Poster record. Nested records of unknown types
+// are ignored, exactly one URI record is required.
+func (r *Record) SmartPoster() (*SmartPoster, error) {
+	if !r.isType(TNFWellKnown, TypeSmartPoster) {
+		return nil, ErrWrongType
+	}
+	message, err := Parse(r.Payload)
+	if err != nil {
+		return nil, err
+	}
+	poster := &SmartPoster{}
+	uris := 0
+	for i := range message.Records {
+		nested := &message.Records[i]
+		switch {
+		case nested.isType(TNFWellKnown, TypeURI):
+			if poster.URI, err = nested.URI(); err != nil {
+				return nil, err
+			}
+			uris++
+		case nested.isType(TNFWellKnown, TypeText):
+			title, err := nested.Text()
+			if err != nil {
+				return nil, err
+			}
+			poster.Titles = append(poster.Titles, title)
+		case nested.isType(TNFWellKnown, typeSmartPosterAction):
+			if len(nested.Payload) != 1 || nested.Payload[0] > byte(ActionEdit-ActionDo) {
+				return nil, ErrInvalidPayload
+			}
+			poster.Action = SmartPosterAction(nested.Payload[0]) + ActionDo
+		case nested.isType(TNFWellKnown, typeSmartPosterSize):
+			if len(nested.Payload) != 4 {
+				return nil, ErrInvalidPayload
+			}
+			p := nested.Payload
+			poster.Size = uint32(p[0])<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3])
+		case nested.isType(TNFWellKnown, typeSmartPosterType):
+			poster.Type = string(nested.Payload)
+		case nested.TNF == TNFMedia:
+			mime := string(nested.Type)
+			if strings.HasPrefix(mime, "image/") || strings.HasPrefix(mime, "video/") {
+				poster.Icons = append(poster.Icons, Icon{MIME: mime, Data: nested.Payload})
+			}
+		}
+	}
+	if uris != 1 {
+		return nil, ErrInvalidPayload
+	}
+	return poster, nil
+}
@@ -0,0 +1,126 @@
+package ndef
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// A Smart Poster with URI, title and action, laid out as in NFC Forum Smart
+// Poster Record Type Definition 1.0 4.
+var smartPosterVector = []byte{
+	0xD1, 0x02, 0x23, 'S', 'p', // MB, ME, SR, well-known
+	0x91, 0x01, 0x0B, 'U', 0x04, 't', 'i', 'n', 'y', 'g', 'o', '.', 'o', 'r', 'g', // MB, SR, "https://"
+	0x11, 0x01, 0x09, 'T', 0x02, 'e', 'n', 'T', 'i', 'n', 'y', 'G', 'o', // SR, UTF-8 in "en"
+	0x51, 0x03, 0x01, 'a', 'c', 't', 0x00, // ME, SR, do the action
+}
+
+func TestSmartPosterMarshal(t *testing.T) {
+	record, err := NewSmartPosterRecord(&SmartPoster{
+		URI:    "https://tinygo.org",
+		Titles: []Text{{Text: "TinyGo", Language: "en"}},
+		Action: ActionDo,
+	})
+	if err != nil {
+		t.Fatalf("NewSmartPosterRecord: %v", err)
+	}
+	got, err := NewMessage(record).Marshal(nil)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(got, smartPosterVector) {
+		t.Errorf("Marshal = % X, want % X", got, smartPosterVector)
+	}
+}
+
+func TestSmartPosterParse(t *testing.T) {
+	message, err := Parse(smartPosterVector)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got, err := message.Records[0].SmartPoster()
+	if err != nil {
+		t.Fatalf("SmartPoster: %v", err)
+	}
+	want := &SmartPoster{
+		URI:    "https://tinygo.org",
+		Titles: []Text{{Text: "TinyGo", Language: "en", Encoding: UTF8}},
+		Action: ActionDo,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SmartPoster = %+v, want %+v", got, want)
+	}
+}
+
+func TestSmartPosterRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		poster *SmartPoster
+	}{
+		{"URI only", &SmartPoster{URI: "tel:+4930123456"}},
+		{"all records", &SmartPoster{
+			URI: "https://www.example.com/menu",
+			Titles: []Text{
+				{Text: "Menu", Language: "en"},
+				{Text: "Speisekarte", Language: "de", Encoding: UTF16},
+			},
+			Action: ActionEdit,
+			Size:   0x01020304,
+			Type:   "text/html",
+			Icons:  []Icon{{MIME: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record, err := NewSmartPosterRecord(test.poster)
+			if err != nil {
+				t.Fatalf("NewSmartPosterRecord: %v", err)
+			}
+			got, err := record.SmartPoster()
+			if err != nil {
+				t.Fatalf("SmartPoster: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.poster) {
+				t.Errorf("SmartPoster = %+v, want %+v", got, test.poster)
+			}
+		})
+	}
+}
+
+func TestSmartPosterErrors(t *testing.T) {
+	uri := NewURIRecord("https://tinygo.org")
+	title, _ := NewTextRecord("TinyGo", "en")
+	poster := func(records ...Record) *Record {
+		payload, err := NewMessage(records...).Marshal(nil)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		return &Record{TNF: TNFWellKnown, Type: TypeSmartPoster, Payload: payload}
+	}
+	action := func(payload ...byte) Record {
+		return Record{TNF: TNFWellKnown, Type: []byte("act"), Payload: payload}
+	}
+	tests := []struct {
+		name   string
+		record *Record
+		want   error
+	}{
+		{"other type", &uri, ErrWrongType},
+		{"no URI", poster(title), ErrInvalidPayload},
+		{"two URIs", poster(uri, uri), ErrInvalidPayload},
+		{"unknown action", poster(uri, action(3)), ErrInvalidPayload},
+		{"action too long", poster(uri, action(0, 0)), ErrInvalidPayload},
+		{"short size", poster(uri, Record{TNF: TNFWellKnown, Type: []byte("s"), Payload: []byte{1}}), ErrInvalidPayload},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.record.SmartPoster(); err != test.want {
+				t.Errorf("SmartPoster: %v, want %v", err, test.want)
+			}
+		})
+	}
+	icon := &SmartPoster{URI: "https://tinygo.org", Icons: []Icon{{MIME: "text/plain"}}}
+	if _, err := NewSmartPosterRecord(icon); err != ErrInvalidPayload {
+		t.Errorf("NewSmartPosterRecord with a text icon: %v, want ErrInvalidPayload", err)
+	}
+}
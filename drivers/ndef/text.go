@@ -0,0 +1,109 @@
+package ndef
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// TextEncoding is the encoding of the text of a Text record.
+type TextEncoding uint8
+
+const (
+	UTF8  TextEncoding = 0
+	UTF16 TextEncoding = 1 // Big endian unless the text starts with a byte order mark
+)
+
+// Status byte of the Text record. See NFC Forum Text Record Type Definition
+// 1.0 3.2.1.
+const (
+	textUTF16       = 0x80
+	textLanguageLen = 0x3F
+)
+
+// Text is the content of a Text record.
+type Text struct {
+	Text     string
+	Language string // IANA language code like "en" or "de-CH"
+	Encoding TextEncoding
+}
+
+// NewTextRecord returns a Text record holding the text in the given language,
+// encoded as UTF-8. ErrInvalidPayload is returned for a language code longer
+// than 63 bytes.
+func NewTextRecord(text string, language string) (Record, error) {
+	return NewTextRecordEncoded(&Text{Text: text, Language: language})
+}
+
+// NewTextRecordEncoded returns a Text record using the encoding of the text.
+// UTF-16 text is stored big endian without byte order mark. ErrInvalidPayload
+// is returned for a language code longer than 63 bytes.
+func NewTextRecordEncoded(text *Text) (Record, error) {
+	if len(text.Language) > textLanguageLen {
+		return Record{}, ErrInvalidPayload
+	}
+	status := byte(len(text.Language))
+	if text.Encoding == UTF16 {
+		status |= textUTF16
+	}
+	payload := append([]byte{status}, text.Language...)
+	if text.Encoding == UTF16 {
+		for _, unit := range utf16.Encode([]rune(text.Text)) {
+			payload = append(payload, byte(unit>>8), byte(unit))
+		}
+	} else {
+		payload = append(payload, text.Text...)
+	}
+	return Record{TNF: TNFWellKnown, Type: append([]byte{}, TypeText...), Payload: payload}, nil
+}
+
+// Text decodes a Text record.
+func (r *Record) Text() (Text, error) {
+	if !r.isType(TNFWellKnown, TypeText) {
+		return Text{}, ErrWrongType
+	}
+	return decodeText(r.Payload)
+}
+
+func decodeText(payload []byte) (Text, error) {
+	text := Text{}
+	if len(payload) < 1 {
+		return text, ErrInvalidPayload
+	}
+	languageLen := int(payload[0] & textLanguageLen)
+	if len(payload) < 1+languageLen {
+		return text, ErrInvalidPayload
+	}
+	text.Language = string(payload[1 : 1+languageLen])
+	data := payload[1+languageLen:]
+	if payload[0]&textUTF16 == 0 {
+		if !utf8.Valid(data) {
+			return text, ErrInvalidPayload
+		}
+		text.Text = string(data)
+		return text, nil
+	}
+	text.Encoding = UTF16
+	if len(data)%2 != 0 {
+		return text, ErrInvalidPayload
+	}
+	bigEndian := true
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFE && data[1] == 0xFF:
+			data = data[2:]
+		case data[0] == 0xFF && data[1] == 0xFE:
+			bigEndian = false
+			data = data[2:]
+		}
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	text.Text = string(utf16.Decode(units))
+	return text, nil
+}
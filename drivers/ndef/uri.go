@@ -0,0 +1,89 @@
+package ndef
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors of the typed record helpers
+var (
+	ErrWrongType      = errors.New("ndef: record of another type")
+	ErrInvalidPayload = errors.New("ndef: invalid payload for the record type")
+)
+
+// Well-known record types. See NFC Forum Record Type Definition 1.0.
+var (
+	TypeURI         = []byte("U")
+	TypeText        = []byte("T")
+	TypeSmartPoster = []byte("Sp")
+)
+
+// isType reports whether the record has the given TNF and type.
+func (r *Record) isType(tnf TNF, typ []byte) bool {
+	return r.TNF == tnf && string(r.Type) == string(typ)
+}
+
+// uriPrefixes are the abbreviations of the URI identifier code. See NFC Forum
+// URI Record Type Definition 1.0 3.2.2.
+var uriPrefixes = [...]string{
+	0x00: "",
+	0x01: "http://www.",
+	0x02: "https://www.",
+	0x03: "http://",
+	0x04: "https://",
+	0x05: "tel:",
+	0x06: "mailto:",
+	0x07: "ftp://anonymous:anonymous@",
+	0x08: "ftp://ftp.",
+	0x09: "ftps://",
+	0x0A: "sftp://",
+	0x0B: "smb://",
+	0x0C: "nfs://",
+	0x0D: "ftp://",
+	0x0E: "dav://",
+	0x0F: "news:",
+	0x10: "telnet://",
+	0x11: "imap:",
+	0x12: "rtsp://",
+	0x13: "urn:",
+	0x14: "pop:",
+	0x15: "sip:",
+	0x16: "sips:",
+	0x17: "tftp:",
+	0x18: "btspp://",
+	0x19: "btl2cap://",
+	0x1A: "btgoep://",
+	0x1B: "tcpobex://",
+	0x1C: "irdaobex://",
+	0x1D: "file://",
+	0x1E: "urn:epc:id:",
+	0x1F: "urn:epc:tag:",
+	0x20: "urn:epc:pat:",
+	0x21: "urn:epc:raw:",
+	0x22: "urn:epc:",
+	0x23: "urn:nfc:",
+}
+
+// NewURIRecord returns a URI record, abbreviated with the longest matching
+// prefix of the identifier code table.
+func NewURIRecord(uri string) Record {
+	code := 0
+	for i, prefix := range uriPrefixes {
+		if strings.HasPrefix(uri, prefix) && len(prefix) > len(uriPrefixes[code]) {
+			code = i
+		}
+	}
+	payload := append([]byte{byte(code)}, uri[len(uriPrefixes[code]):]...)
+	return Record{TNF: TNFWellKnown, Type: append([]byte{}, TypeURI...), Payload: payload}
+}
+
+// URI returns the URI of a URI record, with the prefix expanded.
+func (r *Record) URI() (string, error) {
+	if !r.isType(TNFWellKnown, TypeURI) {
+		return "", ErrWrongType
+	}
+	if len(r.Payload) < 1 || int(r.Payload[0]) >= len(uriPrefixes) {
+		return "", ErrInvalidPayload
+	}
+	return uriPrefixes[r.Payload[0]] + string(r.Payload[1:]), nil
+}
@@ -12,3 +12,18 @@ const (
 	MIFARE_CMD_STORE            = 0xC2 ///< Store
 	MIFARE_ULTRALIGHT_CMD_WRITE = 0xA2 ///< Write (MiFare Ultralight)
 )
+
+// NTAG21x and MIFARE Ultralight EV1 Commands
+const (
+	NTAG_CMD_GET_VERSION = 0x60 ///< Get version, the PN532 takes it for Auth A within InDataExchange
+	NTAG_CMD_READ_SIG    = 0x3C ///< Read the originality signature
+	NTAG_CMD_PWD_AUTH    = 0x1B ///< Password authentication
+	NTAG_CMD_FAST_READ   = 0x3A ///< Read a range of pages
+	NTAG_CMD_READ_CNT    = 0x39 ///< Read the NFC counter
+)
+
+// MIFARE Ultralight C Commands
+const (
+	MIFARE_ULTRALIGHTC_CMD_AUTHENTICATE = 0x1A ///< 3DES authentication, first part
+	MIFARE_ULTRALIGHTC_CMD_CONTINUE     = 0xAF ///< 3DES authentication, further parts
+)
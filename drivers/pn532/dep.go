@@ -0,0 +1,351 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DEP baud rates. See [2] 7.3.3 InJumpForDEP.
+const (
+	DEPBaudRate106 = 0x00 // 106 kbps
+	DEPBaudRate212 = 0x01 // 212 kbps
+	DEPBaudRate424 = 0x02 // 424 kbps
+)
+
+// The amount of data which fits into a single chained DEP frame
+const depChunkSize = BUFFSIZE - 8 - 2
+
+// DEPConfig holds the parameters used by the initiator to activate a target
+// in DEP mode.
+type DEPConfig struct {
+	Active   bool  // Use the active instead of the passive communication mode
+	BaudRate uint8 // One of the DEPBaudRate constants
+	// PassiveInitiatorData selects a specific target in passive mode. At 106
+	// kbps it holds the NFCID1 (4 bytes), at 212/424 kbps the POL_REQ (5 bytes).
+	PassiveInitiatorData []byte
+	NFCID3               []byte // NFCID3i sent in the ATR_REQ (10 bytes)
+	GeneralBytes         []byte // General bytes sent in the ATR_REQ
+}
+
+// DEPTarget describes a target activated in DEP mode, as reported in its
+// ATR_RES.
+type DEPTarget struct {
+	Tg           uint8 // Logical number of the target assigned by the PN532
+	NFCID3       [10]byte
+	DID          uint8 // Device identifier
+	BS           uint8 // Supported send bit rates
+	BR           uint8 // Supported receive bit rates
+	TO           uint8 // Timeout value
+	PP           uint8 // Optional parameters
+	GeneralBytes []byte
+}
+
+// InJumpForDEP activates a target in DEP mode, either in active or passive
+// communication mode. The PN532 handles the anticollision and the ATR on its
+// own. A timeout of 0 waits forever.
+func (d *Device) InJumpForDEP(config *DEPConfig, timeout time.Duration) (DEPTarget, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inJumpForDEP(context.Background(), config, timeout)
+}
+
+// InJumpForDEPContext activates a target in DEP mode. The pending activation
+// is aborted once the context is done.
+func (d *Device) InJumpForDEPContext(ctx context.Context, config *DEPConfig) (DEPTarget, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.inJumpForDEP(ctx, config, 0)
+}
+
+func (d *Device) inJumpForDEP(ctx context.Context, config *DEPConfig, timeout time.Duration) (DEPTarget, error) {
+	target := DEPTarget{}
+	if len(config.NFCID3) != 0 && len(config.NFCID3) != 10 {
+		return target, errors.New("the NFCID3 must be 10 bytes long")
+	}
+	length := 4 + len(config.PassiveInitiatorData) + len(config.NFCID3) + len(config.GeneralBytes)
+	if length > BUFFSIZE-8 {
+		return target, errors.New("the DEP parameters exceed the buffer")
+	}
+	buffer := d.buffer[:length]
+	buffer[0] = COMMAND_INJUMPFORDEP
+	buffer[1] = 0
+	if config.Active {
+		buffer[1] = 1
+	}
+	buffer[2] = config.BaudRate
+	buffer[3] = 0 // Next
+	n := 4
+	if len(config.PassiveInitiatorData) > 0 {
+		buffer[3] |= 0x01
+		n += copy(buffer[n:], config.PassiveInitiatorData)
+	}
+	if len(config.NFCID3) > 0 {
+		buffer[3] |= 0x02
+		n += copy(buffer[n:], config.NFCID3)
+	}
+	if len(config.GeneralBytes) > 0 {
+		buffer[3] |= 0x04
+		copy(buffer[n:], config.GeneralBytes)
+	}
+	response, err := d.exchange(ctx, buffer, d.response[:], timeout)
+	if err != nil {
+		return target, err
+	}
+	if len(response) < 1 {
+		return target, errors.New("invalid InJumpForDEP response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return target, err
+	}
+	return parseATRResponse(response[1:], true)
+}
+
+// InATR sends an ATR_REQ to a target which has already been listed with
+// InListPassiveTarget. The NFCID3 is optional and must be 10 bytes long if
+// given.
+func (d *Device) InATR(tg uint8, nfcid3 []byte, generalBytes []byte) (DEPTarget, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target := DEPTarget{}
+	if len(nfcid3) != 0 && len(nfcid3) != 10 {
+		return target, errors.New("the NFCID3 must be 10 bytes long")
+	}
+	length := 3 + len(nfcid3) + len(generalBytes)
+	if length > BUFFSIZE-8 {
+		return target, errors.New("the ATR parameters exceed the buffer")
+	}
+	buffer := d.buffer[:length]
+	buffer[0] = COMMAND_INATR
+	buffer[1] = tg
+	buffer[2] = 0 // Next
+	n := 3
+	if len(nfcid3) > 0 {
+		buffer[2] |= 0x01
+		n += copy(buffer[n:], nfcid3)
+	}
+	if len(generalBytes) > 0 {
+		buffer[2] |= 0x02
+		copy(buffer[n:], generalBytes)
+	}
+	response, err := d.exchange(context.Background(), buffer, d.response[:], time.Second)
+	if err != nil {
+		return target, err
+	}
+	if len(response) < 1 {
+		return target, errors.New("invalid InATR response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return target, err
+	}
+	target, err = parseATRResponse(response[1:], false)
+	target.Tg = tg
+	return target, err
+}
+
+// InPSL changes the baud rates used to communicate with a DEP target. brit is
+// the baud rate from the initiator to the target and brti the other way
+// round, both are one of the DEPBaudRate constants.
+func (d *Device) InPSL(tg uint8, brit uint8, brti uint8) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	buffer := d.buffer[:4]
+	buffer[0] = COMMAND_INPSL
+	buffer[1] = tg
+	buffer[2] = brit
+	buffer[3] = brti
+	response, err := d.exchange(context.Background(), buffer, d.response[:], time.Second)
+	if err != nil {
+		return err
+	}
+	if len(response) < 1 {
+		return errors.New("invalid InPSL response")
+	}
+	return checkStatus(response[0])
+}
+
+// DEPExchange sends data to a DEP target and copies its answer into response.
+// Data exceeding a single frame is chained, a chained answer is collected
+// until the target is done. It returns the number of bytes received.
+func (d *Device) DEPExchange(tg uint8, data []byte, response []byte, timeout time.Duration) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.depExchangeChained(context.Background(), tg, data, response, timeout)
+}
+
+// DEPExchangeContext sends data to a DEP target and copies its answer into
+// response like DEPExchange. The exchange is aborted once the context is done.
+func (d *Device) DEPExchangeContext(ctx context.Context, tg uint8, data []byte, response []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.depExchangeChained(ctx, tg, data, response, 0)
+}
+
+func (d *Device) depExchangeChained(ctx context.Context, tg uint8, data []byte, response []byte, timeout time.Duration) (int, error) {
+	for len(data) > depChunkSize {
+		// more information follows, the target only acknowledges the chunk
+		if _, err := d.depExchange(ctx, tg|StatusMI, data[:depChunkSize], timeout); err != nil {
+			return 0, err
+		}
+		data = data[depChunkSize:]
+	}
+	received := 0
+	for {
+		answer, err := d.depExchange(ctx, tg, data, timeout)
+		if err != nil {
+			return received, err
+		}
+		if received+len(answer)-1 > len(response) {
+			return received, errors.New("the DEP response exceeds the buffer")
+		}
+		received += copy(response[received:], answer[1:])
+		if answer[0]&StatusMI == 0 {
+			return received, nil
+		}
+		// fetch the next chunk of the chained answer
+		data = nil
+	}
+}
+
+// depExchange sends a single DEP frame and returns the status byte followed
+// by the data received.
+func (d *Device) depExchange(ctx context.Context, tg uint8, data []byte, timeout time.Duration) ([]byte, error) {
+	buffer := d.buffer[:2+len(data)]
+	buffer[0] = COMMAND_INDATAEXCHANGE
+	buffer[1] = tg
+	copy(buffer[2:], data)
+	response, err := d.exchange(ctx, buffer, d.response[:], timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 {
+		return nil, errors.New("invalid InDataExchange response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// TgDEPReceive waits for data from the initiator while the PN532 acts as DEP
+// target and copies it into buffer. Chained data is collected until the
+// initiator is done. It returns the number of bytes received.
+func (d *Device) TgDEPReceive(buffer []byte, timeout time.Duration) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tgDEPReceive(context.Background(), buffer, timeout)
+}
+
+// TgDEPReceiveContext waits for data from the initiator like TgDEPReceive
+// until the context is done.
+func (d *Device) TgDEPReceiveContext(ctx context.Context, buffer []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tgDEPReceive(ctx, buffer, 0)
+}
+
+func (d *Device) tgDEPReceive(ctx context.Context, buffer []byte, timeout time.Duration) (int, error) {
+	received := 0
+	for {
+		command := d.buffer[:1]
+		command[0] = COMMAND_TGGETDATA
+		response, err := d.exchange(ctx, command, d.response[:], timeout)
+		if err != nil {
+			return received, err
+		}
+		if len(response) < 1 {
+			return received, errors.New("invalid TgGetData response")
+		}
+		if err := checkStatus(response[0]); err != nil {
+			return received, err
+		}
+		if received+len(response)-1 > len(buffer) {
+			return received, errors.New("the DEP data exceeds the buffer")
+		}
+		received += copy(buffer[received:], response[1:])
+		if response[0]&StatusMI == 0 {
+			return received, nil
+		}
+	}
+}
+
+// TgDEPSend answers the initiator while the PN532 acts as DEP target. Data
+// exceeding a single frame is chained.
+func (d *Device) TgDEPSend(data []byte, timeout time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for len(data) > depChunkSize {
+		if err := d.targetSend(COMMAND_TGSETMETADATA, data[:depChunkSize], timeout); err != nil {
+			return err
+		}
+		data = data[depChunkSize:]
+	}
+	return d.targetSend(COMMAND_TGSETDATA, data, timeout)
+}
+
+// parseATRResponse decodes the ATR_RES fields reported by InJumpForDEP and
+// InATR. InJumpForDEP reports the logical target number in front of them.
+func parseATRResponse(response []byte, withTg bool) (DEPTarget, error) {
+	target := DEPTarget{}
+	if withTg {
+		if len(response) < 1 {
+			return target, errors.New("invalid ATR_RES")
+		}
+		target.Tg = response[0]
+		response = response[1:]
+	}
+	const fixedLen = 15
+	if len(response) < fixedLen {
+		return target, errors.New("invalid ATR_RES")
+	}
+	copy(target.NFCID3[:], response[:10])
+	target.DID = response[10]
+	target.BS = response[11]
+	target.BR = response[12]
+	target.TO = response[13]
+	target.PP = response[14]
+	target.GeneralBytes = make([]byte, len(response)-fixedLen)
+	copy(target.GeneralBytes, response[fixedLen:])
+	return target, nil
+}
+
+// DEPLink provides the half-duplex send and receive of a DEP session, as
+// needed by protocols like LLCP. The initiator sends the data given to Send
+// with the next Receive, the target answers with Send what it got by Receive.
+type DEPLink struct {
+	dev       *Device
+	tg        uint8
+	initiator bool
+	timeout   time.Duration
+	pending   []byte
+}
+
+// NewDEPInitiatorLink creates a link to the target activated by InJumpForDEP
+// or InATR.
+func NewDEPInitiatorLink(device *Device, tg uint8, timeout time.Duration) DEPLink {
+	return DEPLink{dev: device, tg: tg, initiator: true, timeout: timeout}
+}
+
+// NewDEPTargetLink creates a link to the initiator which has activated the
+// PN532 by TgInitAsTarget.
+func NewDEPTargetLink(device *Device, timeout time.Duration) DEPLink {
+	return DEPLink{dev: device, timeout: timeout}
+}
+
+// Send transmits data to the peer. The initiator only stores the data until
+// the next Receive.
+func (l *DEPLink) Send(data []byte) error {
+	if l.initiator {
+		l.pending = append(l.pending[:0], data...)
+		return nil
+	}
+	return l.dev.TgDEPSend(data, l.timeout)
+}
+
+// Receive copies the next data sent by the peer into buffer.
+func (l *DEPLink) Receive(buffer []byte) (int, error) {
+	if l.initiator {
+		return l.dev.DEPExchange(l.tg, l.pending, buffer, l.timeout)
+	}
+	return l.dev.TgDEPReceive(buffer, l.timeout)
+}
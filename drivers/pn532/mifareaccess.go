@@ -0,0 +1,127 @@
+package pn532
+
+import "errors"
+
+// ErrInvalidAccessBits is returned for access bits whose inverted copies do
+// not match. Writing them to a sector trailer makes the sector unusable.
+var ErrInvalidAccessBits = errors.New("invalid MIFARE Classic access bits")
+
+// Access conditions of the data blocks and the sector trailer, encoded as
+// C1 C2 C3. See NXP MF1S50YYX 8.7 Memory access.
+const (
+	MifareClassicDataTransport    = 0b000 // Read and write with key A or B
+	MifareClassicDataReadAB       = 0b010 // Read only with key A or B
+	MifareClassicDataWriteB       = 0b100 // Read with key A or B, write with key B
+	MifareClassicDataValue        = 0b110 // Value block, increment with key B, decrement with key A or B
+	MifareClassicDataDecrement    = 0b001 // Value block, decrement only with key A or B
+	MifareClassicDataReadWriteB   = 0b011 // Read and write with key B only
+	MifareClassicDataReadB        = 0b101 // Read only with key B
+	MifareClassicDataNeverAccess  = 0b111 // No access at all
+	MifareClassicTrailerTransport = 0b001 // Key A writes the keys and access bits, key B is readable
+	MifareClassicTrailerKeyB      = 0b011 // Key B writes the keys and access bits
+	MifareClassicTrailerReadOnly  = 0b110 // Access bits readable, nothing writable
+)
+
+// MifareClassicAccessBits holds the access conditions of a sector. Each
+// condition is the 3 bit value C1 C2 C3 with C1 as most significant bit.
+// Conditions 0 to 2 belong to the data blocks, condition 3 to the sector
+// trailer. In the 16 block sectors of the 4K card each data condition
+// covers 5 blocks.
+type MifareClassicAccessBits struct {
+	Conditions [4]uint8
+	GPB        uint8 // General purpose byte, not used by the card itself
+}
+
+// MifareClassicTransportAccessBits are the access bits cards are shipped
+// with: FF 07 80 69.
+var MifareClassicTransportAccessBits = MifareClassicAccessBits{
+	Conditions: [4]uint8{
+		MifareClassicDataTransport,
+		MifareClassicDataTransport,
+		MifareClassicDataTransport,
+		MifareClassicTrailerTransport,
+	},
+	GPB: 0x69,
+}
+
+// KeyBReadable reports whether the sector trailer condition allows to read
+// key B, which then is data instead of a key.
+func (a MifareClassicAccessBits) KeyBReadable() bool {
+	switch a.Conditions[3] {
+	case 0b000, 0b010, MifareClassicTrailerTransport:
+		return true
+	}
+	return false
+}
+
+// Encode returns the bytes 6 to 9 of the sector trailer.
+func (a MifareClassicAccessBits) Encode() [4]byte {
+	var c1, c2, c3 byte
+	for i, condition := range a.Conditions {
+		c1 |= (condition >> 2 & 1) << i
+		c2 |= (condition >> 1 & 1) << i
+		c3 |= (condition & 1) << i
+	}
+	return [4]byte{
+		(^c2&0x0F)<<4 | ^c1&0x0F,
+		c1<<4 | ^c3&0x0F,
+		c3<<4 | c2,
+		a.GPB,
+	}
+}
+
+// DecodeMifareClassicAccessBits decodes the bytes 6 to 9 of a sector trailer.
+// It returns ErrInvalidAccessBits if the inverted copies of the bits do not
+// match.
+func DecodeMifareClassicAccessBits(data []byte) (MifareClassicAccessBits, error) {
+	access := MifareClassicAccessBits{}
+	if len(data) < 4 {
+		return access, errors.New("access bits need 4 bytes")
+	}
+	c1, c2, c3 := data[1]>>4, data[2]&0x0F, data[2]>>4
+	if ^c1&0x0F != data[0]&0x0F || ^c2&0x0F != data[0]>>4 || ^c3&0x0F != data[1]&0x0F {
+		return access, ErrInvalidAccessBits
+	}
+	for i := range access.Conditions {
+		access.Conditions[i] = (c1>>i&1)<<2 | (c2>>i&1)<<1 | c3>>i&1
+	}
+	access.GPB = data[3]
+	return access, nil
+}
+
+// MifareClassicTrailer is the content of a sector trailer.
+type MifareClassicTrailer struct {
+	KeyA   MifareClassicKey
+	Access MifareClassicAccessBits
+	KeyB   MifareClassicKey
+}
+
+// Build composes the 16 byte sector trailer.
+func (t *MifareClassicTrailer) Build() ([MifareClassicBlockSize]byte, error) {
+	var block [MifareClassicBlockSize]byte
+	if len(t.KeyA) != 6 || len(t.KeyB) != 6 {
+		return block, errors.New("MIFARE Classic keys need 6 bytes")
+	}
+	access := t.Access.Encode()
+	copy(block[0:6], t.KeyA)
+	copy(block[6:10], access[:])
+	copy(block[10:16], t.KeyB)
+	return block, nil
+}
+
+// ParseMifareClassicTrailer decodes a sector trailer. Key A always reads as
+// zeros, key B does as well unless the access conditions allow to read it.
+func ParseMifareClassicTrailer(block []byte) (MifareClassicTrailer, error) {
+	trailer := MifareClassicTrailer{}
+	if len(block) != MifareClassicBlockSize {
+		return trailer, errors.New("a sector trailer needs 16 bytes")
+	}
+	access, err := DecodeMifareClassicAccessBits(block[6:10])
+	if err != nil {
+		return trailer, err
+	}
+	trailer.KeyA = append(MifareClassicKey{}, block[0:6]...)
+	trailer.Access = access
+	trailer.KeyB = append(MifareClassicKey{}, block[10:16]...)
+	return trailer, nil
+}
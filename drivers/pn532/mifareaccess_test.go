@@ -0,0 +1,134 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Access bits of NXP MF1S50YYX 8.7 and NXP AN10787 MIFARE Application
+// Directory 3.7.
+var accessBitsVectors = []struct {
+	name   string
+	access MifareClassicAccessBits
+	data   [4]byte
+}{
+	{"transport", MifareClassicTransportAccessBits, [4]byte{0xFF, 0x07, 0x80, 0x69}},
+	{
+		"MAD sector",
+		MifareClassicAccessBits{
+			Conditions: [4]uint8{
+				MifareClassicDataWriteB,
+				MifareClassicDataWriteB,
+				MifareClassicDataWriteB,
+				MifareClassicTrailerKeyB,
+			},
+			GPB: 0xC1,
+		},
+		[4]byte{0x78, 0x77, 0x88, 0xC1},
+	},
+	{
+		"key B only",
+		MifareClassicAccessBits{
+			Conditions: [4]uint8{
+				MifareClassicDataReadWriteB,
+				MifareClassicDataReadWriteB,
+				MifareClassicDataReadWriteB,
+				MifareClassicTrailerKeyB,
+			},
+		},
+		[4]byte{0x0F, 0x00, 0xFF, 0x00},
+	},
+	{
+		"never",
+		MifareClassicAccessBits{
+			Conditions: [4]uint8{
+				MifareClassicDataNeverAccess,
+				MifareClassicDataNeverAccess,
+				MifareClassicDataNeverAccess,
+				0b111,
+			},
+		},
+		[4]byte{0x00, 0xF0, 0xFF, 0x00},
+	},
+}
+
+func TestMifareClassicAccessBitsEncode(t *testing.T) {
+	for _, vector := range accessBitsVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			if got := vector.access.Encode(); got != vector.data {
+				t.Errorf("Encode = %X, want %X", got, vector.data)
+			}
+		})
+	}
+}
+
+func TestDecodeMifareClassicAccessBits(t *testing.T) {
+	for _, vector := range accessBitsVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			got, err := DecodeMifareClassicAccessBits(vector.data[:])
+			if err != nil {
+				t.Fatalf("DecodeMifareClassicAccessBits: %v", err)
+			}
+			if got != vector.access {
+				t.Errorf("DecodeMifareClassicAccessBits = %+v, want %+v", got, vector.access)
+			}
+		})
+	}
+}
+
+func TestDecodeMifareClassicAccessBitsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"C1 not inverted", []byte{0xFE, 0x07, 0x80, 0x69}},
+		{"C2 not inverted", []byte{0xEF, 0x07, 0x80, 0x69}},
+		{"C3 not inverted", []byte{0xFF, 0x06, 0x80, 0x69}},
+		{"all zeros", []byte{0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeMifareClassicAccessBits(test.data); err != ErrInvalidAccessBits {
+				t.Errorf("DecodeMifareClassicAccessBits: %v, want ErrInvalidAccessBits", err)
+			}
+		})
+	}
+	if _, err := DecodeMifareClassicAccessBits([]byte{0xFF, 0x07, 0x80}); err == nil {
+		t.Error("3 bytes accepted")
+	}
+}
+
+func TestMifareClassicAccessBitsKeyBReadable(t *testing.T) {
+	for condition := uint8(0); condition < 8; condition++ {
+		access := MifareClassicAccessBits{Conditions: [4]uint8{3: condition}}
+		want := condition == 0b000 || condition == 0b010 || condition == 0b001
+		if got := access.KeyBReadable(); got != want {
+			t.Errorf("KeyBReadable of trailer condition %03b = %v, want %v", condition, got, want)
+		}
+	}
+}
+
+func TestMifareClassicTrailer(t *testing.T) {
+	block := []byte{
+		0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5,
+		0x78, 0x77, 0x88, 0xC1,
+		0xB0, 0xB1, 0xB2, 0xB3, 0xB4, 0xB5,
+	}
+	trailer, err := ParseMifareClassicTrailer(block)
+	if err != nil {
+		t.Fatalf("ParseMifareClassicTrailer: %v", err)
+	}
+	if trailer.Access != accessBitsVectors[1].access {
+		t.Errorf("access bits %+v, want %+v", trailer.Access, accessBitsVectors[1].access)
+	}
+	built, err := trailer.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if !bytes.Equal(built[:], block) {
+		t.Errorf("Build = %X, want %X", built, block)
+	}
+	if _, err := (&MifareClassicTrailer{KeyA: MifareClassicKey{1}, KeyB: trailer.KeyB}).Build(); err == nil {
+		t.Error("Build accepted a 1 byte key A")
+	}
+}
@@ -1,8 +1,8 @@
 package pn532
 
 import (
-	"encoding/hex"
 	"errors"
+	"strconv"
 	"time"
 )
 
@@ -10,8 +10,18 @@ type (
 	MifareClassicKeyType uint8
 	MifareClassicKey     []byte
 	MifareClassic        struct {
-		dev  *Device
+		dev *Device
+		// the default keys, used for every sector without own keys
 		keys [2]MifareClassicKey
+		// the keys per sector and key type, nil if the default applies
+		sectorKeys [mifareClassicMaxSectors][2]MifareClassicKey
+		geometry   MifareClassicGeometry
+		// allow writing sector trailers with malformed access bits
+		allowInvalidAccessBits bool
+		// how data shorter than a block is written
+		padding MifareClassicPadding
+		// read back every written block
+		verify bool
 	}
 )
 
@@ -22,6 +32,31 @@ const (
 
 const MifareClassicBlockSize = 16
 
+// MifareClassicPadding selects how WriteDataBlock handles data shorter than a
+// block.
+type MifareClassicPadding uint8
+
+const (
+	MifareClassicPadNone     MifareClassicPadding = iota // Refuse partial blocks
+	MifareClassicPadZero                                 // Fill the rest of the block with zeros
+	MifareClassicPadPreserve                             // Keep the rest of the block as stored on the card
+)
+
+// ErrPartialBlock is returned for data shorter than a block without padding.
+var ErrPartialBlock = errors.New("the given data is shorter than a block")
+
+// MifareClassicVerifyError is returned if a block read back after writing
+// differs from the written data.
+type MifareClassicVerifyError struct {
+	Block   uint8
+	Written [MifareClassicBlockSize]byte
+	Read    [MifareClassicBlockSize]byte
+}
+
+func (e *MifareClassicVerifyError) Error() string {
+	return "MIFARE Classic block " + strconv.Itoa(int(e.Block)) + " differs after writing"
+}
+
 func NewMifareClasic(device *Device) MifareClassic {
 	return MifareClassic{
 		dev: device,
@@ -29,6 +64,7 @@ func NewMifareClasic(device *Device) MifareClassic {
 			{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
 			{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
 		},
+		geometry: MifareClassic1K,
 	}
 }
 
@@ -39,105 +75,185 @@ func (m *MifareClassic) selectKeyCommand(number MifareClassicKeyType) byte {
 	return MIFARE_CMD_AUTH_B
 }
 
+// AuthenticateBlock authenticates the sector of the block with the key stored
+// for the sector, see SetSectorKey.
 func (m *MifareClassic) AuthenticateBlock(uid []byte,
 	blockNumber uint32,
 	keyNumber MifareClassicKeyType,
 ) error {
-	const commandLen = 10
-	buffer := make([]byte, commandLen+len(uid))
-	buffer[0] = COMMAND_INDATAEXCHANGE /* Data Exchange Header */
-	buffer[1] = 1                      /* Max card numbers */
-	buffer[2] = m.selectKeyCommand(keyNumber)
-	buffer[3] = byte(blockNumber)
-	copy(buffer[4:], m.keys[keyNumber])
-	copy(buffer[10:], uid)
-	m.dev.printBuffer("Auth Buffer: ", buffer)
-	if err := m.dev.sendCommandCheckAck(buffer, 100*time.Millisecond); err != nil {
-		return err
-	}
-	buffer = make([]byte, 12)
-	if err := m.dev.readdata(buffer); err != nil {
-		return err
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	key := m.SectorKey(m.geometry.SectorOfBlock(blockNumber), keyNumber)
+	return m.authenticate(uid, blockNumber, keyNumber, key)
+}
+
+// authenticate authenticates the sector of the block with the given key. The
+// lock of the device must be held.
+func (m *MifareClassic) authenticate(uid []byte,
+	blockNumber uint32,
+	keyNumber MifareClassicKeyType,
+	key MifareClassicKey,
+) error {
+	if len(key) != 6 {
+		return errors.New("MIFARE Classic keys need 6 bytes")
 	}
-	// check if the response is valid and we are authenticated???
-	// for an auth success it should be bytes 5-7: 0xD5 0x41 0x00
-	// Mifare auth error is technically byte 7: 0x14 but anything other and 0x00
-	// is not good
-	m.dev.printBuffer("Auth response", buffer)
-	if buffer[7] != 0x00 {
-		message := "Mifare auth error, expected 0x00 got: 0x" + hex.EncodeToString(buffer[7:7])
-		return errors.New(message)
+	if len(uid) < 4 {
+		return errors.New("the UID needs at least 4 bytes")
 	}
-	return nil
+	// Crypto1 is initialized with the last 4 bytes of 7 and 10 byte UIDs.
+	// See NXP AN10927 MIFARE and handling of UIDs.
+	var buffer [12]byte
+	buffer[0] = m.selectKeyCommand(keyNumber)
+	buffer[1] = byte(blockNumber)
+	copy(buffer[2:], key)
+	copy(buffer[8:], uid[len(uid)-4:])
+	m.dev.printBuffer("Auth Buffer: ", buffer[:])
+	// a failed authentication answers with StatusAuthentication
+	_, err := m.dev.inDataExchange(buffer[:], 100*time.Millisecond)
+	return err
 }
 
 func (m *MifareClassic) ReadDataBlock(blockNumber uint8) ([]byte, error) {
-	buffer := m.dev.buffer[:4]
-	buffer[0] = COMMAND_INDATAEXCHANGE /* Data Exchange Header */
-	buffer[1] = 1                      /* Card number */
-	buffer[2] = MIFARE_CMD_READ        /* Card number */
-	buffer[3] = blockNumber
-	if err := m.dev.sendCommandCheckAck(buffer, 100*time.Millisecond); err != nil {
-		return []byte{}, err
-	}
-	buffer = make([]byte, 26)
-	if err := m.dev.readdata(buffer); err != nil {
-		return []byte{}, err
-	}
-	m.dev.printBuffer("read response", buffer)
-	if buffer[7] != 0x00 {
-		message := "Mifare read response, expected 0x00 got: 0x" + hex.EncodeToString(buffer[8:8])
-		return []byte{}, errors.New(message)
-	}
-	data := make([]byte, 16)
-	copy(data, buffer[8:8+len(data)])
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	return m.readDataBlock(blockNumber)
+}
+
+func (m *MifareClassic) readDataBlock(blockNumber uint8) ([]byte, error) {
+	command := [2]byte{MIFARE_CMD_READ, blockNumber}
+	// a block the card refuses to read answers with a StatusError
+	response, err := m.dev.inDataExchange(command[:], 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < MifareClassicBlockSize {
+		return nil, errors.New("MIFARE Classic read returned " + strconv.Itoa(len(response)) + " bytes")
+	}
+	data := make([]byte, MifareClassicBlockSize)
+	copy(data, response)
 	m.dev.printBuffer("data buffer", data)
 	return data, nil
 }
 
 func (m *MifareClassic) WriteDataBlock(blockNumber uint8, data []byte) error {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	return m.writeDataBlock(blockNumber, data)
+}
+
+func (m *MifareClassic) writeDataBlock(blockNumber uint8, data []byte) error {
 	if len(data) > MifareClassicBlockSize {
 		return errors.New("The given data exceeds the block size")
 	}
-	buffer := m.dev.buffer[:20]
-	buffer[0] = COMMAND_INDATAEXCHANGE
-	buffer[1] = 1 // card number
-	buffer[2] = MIFARE_CMD_WRITE
-	buffer[3] = blockNumber // Block Number (0..63 for 1K, 0..255 for 4K)
-	copy(buffer[4:], data)
-	m.dev.printBuffer("data buffer", data)
-
-	if err := m.dev.sendCommandCheckAck(buffer, 100*time.Millisecond); err != nil {
+	var block [MifareClassicBlockSize]byte
+	if len(data) < MifareClassicBlockSize {
+		switch m.padding {
+		case MifareClassicPadZero:
+		case MifareClassicPadPreserve:
+			current, err := m.readDataBlock(blockNumber)
+			if err != nil {
+				return err
+			}
+			copy(block[:], current)
+		default:
+			return ErrPartialBlock
+		}
+	}
+	copy(block[:], data)
+	if m.geometry.IsTrailerBlock(uint32(blockNumber)) && !m.allowInvalidAccessBits {
+		// a malformed sector trailer locks the sector for good
+		if len(data) != MifareClassicBlockSize {
+			return errors.New("a sector trailer needs 16 bytes")
+		}
+		if _, err := DecodeMifareClassicAccessBits(data[6:10]); err != nil {
+			return err
+		}
+	}
+	command := [2 + MifareClassicBlockSize]byte{MIFARE_CMD_WRITE, blockNumber}
+	copy(command[2:], block[:])
+	m.dev.printBuffer("data buffer", block[:])
+	if _, err := m.dev.inDataExchange(command[:], 100*time.Millisecond); err != nil {
 		return err
 	}
-	// Give the PN532 some time to perfrom the write
-	time.Sleep(10 * time.Millisecond)
-	buffer = make([]byte, 26)
-	if err := m.dev.readdata(buffer); err != nil {
+	if m.verify {
+		return m.verifyDataBlock(blockNumber, block)
+	}
+	return nil
+}
+
+// verifyDataBlock reads the block back and compares it with the written
+// data. The keys of a sector trailer are not readable, so only its access
+// bits are compared.
+func (m *MifareClassic) verifyDataBlock(blockNumber uint8, written [MifareClassicBlockSize]byte) error {
+	current, err := m.readDataBlock(blockNumber)
+	if err != nil {
 		return err
 	}
-	m.dev.printBuffer("write response", buffer)
+	verifyErr := &MifareClassicVerifyError{Block: blockNumber, Written: written}
+	copy(verifyErr.Read[:], current)
+	compare := verifyErr.Read == written
+	if m.geometry.IsTrailerBlock(uint32(blockNumber)) {
+		compare = string(verifyErr.Read[6:10]) == string(written[6:10])
+	}
+	if !compare {
+		return verifyErr
+	}
 	return nil
 }
 
+// SetKeyA sets the default key A, used for all sectors without own key.
 func (m *MifareClassic) SetKeyA(key MifareClassicKey) {
 	m.keys[MifareClassicKeyA] = key
 }
 
+// SetKeyB sets the default key B, used for all sectors without own key.
 func (m *MifareClassic) SetKeyB(key MifareClassicKey) {
 	m.keys[MifareClassicKeyB] = key
 }
 
-func (m *MifareClassic) IsFirstBlock(block uint32) bool {
-	if block < 128 {
-		return (block%4 == 0)
+// WriteSectorTrailer composes the sector trailer and writes it to the card.
+func (m *MifareClassic) WriteSectorTrailer(sector uint8, trailer *MifareClassicTrailer) error {
+	block, err := trailer.Build()
+	if err != nil {
+		return err
 	}
-	return (block%16 == 0)
+	return m.WriteDataBlock(uint8(m.geometry.TrailerBlock(sector)), block[:])
+}
+
+// AllowInvalidAccessBits disables the validation of the access bits when a
+// sector trailer is written by WriteDataBlock. Malformed access bits make the
+// sector permanently unusable, only use this when you know what you do.
+func (m *MifareClassic) AllowInvalidAccessBits(allow bool) {
+	m.allowInvalidAccessBits = allow
+}
+
+// SetPadding selects how data shorter than a block is written, by default
+// it is refused.
+func (m *MifareClassic) SetPadding(padding MifareClassicPadding) {
+	m.padding = padding
+}
+
+// VerifyWrites enables reading back every written block. A block differing
+// from the written data fails the write with a MifareClassicVerifyError.
+func (m *MifareClassic) VerifyWrites(verify bool) {
+	m.verify = verify
+}
+
+// SetGeometry sets the memory layout of the card, by default a 1K card is
+// assumed. Use MifareClassicGeometryFromSAK to detect it.
+func (m *MifareClassic) SetGeometry(geometry MifareClassicGeometry) {
+	m.geometry = geometry
+}
+
+// Geometry returns the memory layout of the card.
+func (m *MifareClassic) Geometry() MifareClassicGeometry {
+	return m.geometry
+}
+
+func (m *MifareClassic) IsFirstBlock(block uint32) bool {
+	return m.geometry.IsFirstBlock(block)
 }
 
 func (m *MifareClassic) IsTrailerBlock(block uint32) bool {
-	if block < 128 {
-		return ((block+1)%4 == 0)
-	}
-	return ((block+1)%16 == 0)
+	return m.geometry.IsTrailerBlock(block)
 }
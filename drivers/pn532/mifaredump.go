@@ -0,0 +1,262 @@
+package pn532
+
+import (
+	"errors"
+	"strconv"
+)
+
+// MifareClassicDump is the content of a whole MIFARE Classic card.
+type MifareClassicDump struct {
+	UID      []byte
+	ATQA     uint16 // SENS_RES
+	SAK      uint8  // SEL_RES
+	Geometry MifareClassicGeometry
+	// Blocks holds the content of every block of the card. The keys found
+	// are filled into the sector trailers, as the card itself never reveals
+	// key A.
+	Blocks [][MifareClassicBlockSize]byte
+	// Readable reports per block whether it could be read, unreadable
+	// blocks are zero.
+	Readable []bool
+	// Keys holds the keys which authenticated each sector.
+	Keys []MifareClassicSectorKeys
+}
+
+// newMifareClassicDump allocates an empty dump of the given geometry.
+func newMifareClassicDump(geometry MifareClassicGeometry) *MifareClassicDump {
+	return &MifareClassicDump{
+		Geometry: geometry,
+		Blocks:   make([][MifareClassicBlockSize]byte, geometry.TotalBlocks()),
+		Readable: make([]bool, geometry.TotalBlocks()),
+		Keys:     make([]MifareClassicSectorKeys, geometry.Sectors),
+	}
+}
+
+// MifareClassicRestoreOptions control RestoreDump.
+type MifareClassicRestoreOptions struct {
+	// Trailers writes the sector trailers as well. This changes the keys and
+	// access conditions of the card.
+	Trailers bool
+	// DryRun only compares the card against the dump without writing.
+	DryRun bool
+}
+
+// MifareClassicBlockDiff is a block whose content on the card differs from
+// the dump.
+type MifareClassicBlockDiff struct {
+	Block uint32
+	Old   [MifareClassicBlockSize]byte // The content of the card
+	New   [MifareClassicBlockSize]byte // The content of the dump
+}
+
+// Dump reads the whole card. Every sector is authenticated with key A and
+// then key B as stored by SetSectorKey or RecoverKeys. Sectors and blocks
+// which can not be read are marked in the dump instead of failing it.
+func (m *MifareClassic) Dump(target Target) (*MifareClassicDump, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	dump := newMifareClassicDump(m.geometry)
+	dump.UID = append([]byte{}, target.UID...)
+	dump.ATQA = target.SensRes
+	dump.SAK = target.SelRes
+	for sector := uint8(0); sector < m.geometry.Sectors; sector++ {
+		if err := m.dumpSector(dump, sector); err != nil {
+			return dump, err
+		}
+	}
+	return dump, nil
+}
+
+// dumpSector reads the blocks of the sector not yet read with every key
+// which authenticates the sector. A block refused by the access conditions is
+// skipped, the card is selected and authenticated again for the next block.
+// The lock of the device must be held.
+func (m *MifareClassic) dumpSector(dump *MifareClassicDump, sector uint8) error {
+	first := m.geometry.FirstBlock(sector)
+	trailer := m.geometry.TrailerBlock(sector)
+	for _, keyNumber := range []MifareClassicKeyType{MifareClassicKeyA, MifareClassicKeyB} {
+		key := m.SectorKey(sector, keyNumber)
+		if err := m.authenticate(dump.UID, first, keyNumber, key); err != nil {
+			if err := m.recover(err); err != nil {
+				return err
+			}
+			continue
+		}
+		if keyNumber == MifareClassicKeyA {
+			dump.Keys[sector].KeyA = key
+		} else {
+			dump.Keys[sector].KeyB = key
+		}
+		authenticated := true
+		for block := first; block <= trailer; block++ {
+			if dump.Readable[block] {
+				continue
+			}
+			if !authenticated {
+				// the card halted after refusing the previous block
+				if err := m.authenticate(dump.UID, first, keyNumber, key); err != nil {
+					if err := m.recover(err); err != nil {
+						return err
+					}
+					break
+				}
+				authenticated = true
+			}
+			data, err := m.readDataBlock(uint8(block))
+			if err != nil {
+				if err := m.recover(err); err != nil {
+					return err
+				}
+				authenticated = false
+				continue
+			}
+			copy(dump.Blocks[block][:], data)
+			dump.Readable[block] = true
+		}
+	}
+	if dump.Readable[trailer] {
+		if key := dump.Keys[sector].KeyA; key != nil {
+			copy(dump.Blocks[trailer][0:6], key)
+		}
+		if key := dump.Keys[sector].KeyB; key != nil {
+			copy(dump.Blocks[trailer][10:16], key)
+		}
+	}
+	return nil
+}
+
+// RestoreDump writes the data blocks of the dump to the card. The
+// manufacturer block and blocks not readable when dumping are skipped, the
+// sector trailers are only written if requested and only if key A and key B
+// are known, unless the access bits make key B readable. Blocks already
+// matching the dump are not written, the keys a card hides are only compared
+// with the key which authenticated the sector. The differences found are
+// returned, with DryRun set nothing is written at all.
+func (m *MifareClassic) RestoreDump(uid []byte, dump *MifareClassicDump, options MifareClassicRestoreOptions) ([]MifareClassicBlockDiff, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	if dump.Geometry.Sectors > m.geometry.Sectors || len(dump.Blocks) != int(dump.Geometry.TotalBlocks()) {
+		return nil, errors.New("the dump does not fit the card")
+	}
+	if options.Trailers {
+		// a trailer with a key not known when dumping locks the sector, a
+		// hidden key B reads as zeros
+		for sector := uint8(0); sector < dump.Geometry.Sectors; sector++ {
+			block := dump.Geometry.TrailerBlock(sector)
+			if !dump.Readable[block] {
+				continue
+			}
+			trailer := dump.Blocks[block]
+			if dump.Keys[sector].KeyA == nil {
+				return nil, errors.New("key A of sector " + strconv.Itoa(int(sector)) + " is unknown")
+			}
+			access, err := DecodeMifareClassicAccessBits(trailer[6:10])
+			if err != nil {
+				return nil, err
+			}
+			if dump.Keys[sector].KeyB == nil && !access.KeyBReadable() {
+				return nil, errors.New("key B of sector " + strconv.Itoa(int(sector)) + " is unknown")
+			}
+		}
+	}
+	var diffs []MifareClassicBlockDiff
+	for sector := uint8(0); sector < dump.Geometry.Sectors; sector++ {
+		var err error
+		diffs, err = m.restoreSector(uid, dump, sector, options, diffs)
+		if err != nil {
+			return diffs, err
+		}
+	}
+	return diffs, nil
+}
+
+// restoreSector compares and writes the blocks of the sector, with key A
+// first and key B for the blocks only writable with key B. The lock of the
+// device must be held.
+func (m *MifareClassic) restoreSector(uid []byte, dump *MifareClassicDump, sector uint8, options MifareClassicRestoreOptions, diffs []MifareClassicBlockDiff) ([]MifareClassicBlockDiff, error) {
+	first := m.geometry.FirstBlock(sector)
+	trailer := m.geometry.TrailerBlock(sector)
+	pending := make([]uint32, 0, m.geometry.BlocksInSector(sector))
+	for block := first; block <= trailer; block++ {
+		if block == 0 || !dump.Readable[block] || (block == trailer && !options.Trailers) {
+			continue
+		}
+		pending = append(pending, block)
+	}
+	for _, keyNumber := range []MifareClassicKeyType{MifareClassicKeyA, MifareClassicKeyB} {
+		if len(pending) == 0 {
+			return diffs, nil
+		}
+		key := m.SectorKey(sector, keyNumber)
+		if err := m.authenticate(uid, first, keyNumber, key); err != nil {
+			if err := m.recover(err); err != nil {
+				return diffs, err
+			}
+			continue
+		}
+		for len(pending) > 0 {
+			block := pending[0]
+			diff := MifareClassicBlockDiff{Block: block, New: dump.Blocks[block]}
+			old, err := m.readDataBlock(uint8(block))
+			if err != nil {
+				// every condition allowing to write the block allows to
+				// read it, so retry with the other key
+				if err := m.recover(err); err != nil {
+					return diffs, err
+				}
+				break
+			}
+			copy(diff.Old[:], old)
+			current := diff.Old
+			if block == trailer {
+				unmaskTrailer(&current, &diff.New, keyNumber, key)
+			}
+			if current == diff.New {
+				pending = pending[1:]
+				continue
+			}
+			if !options.DryRun {
+				if err := m.writeDataBlock(uint8(block), diff.New[:]); err != nil {
+					if err := m.recover(err); err != nil {
+						return diffs, err
+					}
+					break
+				}
+			}
+			diffs = append(diffs, diff)
+			pending = pending[1:]
+		}
+	}
+	if len(pending) > 0 {
+		return diffs, errors.New("block " + strconv.Itoa(int(pending[0])) + " could not be restored")
+	}
+	return diffs, nil
+}
+
+// unmaskTrailer fills the keys of a sector trailer read from the card, which
+// the card returns as zeros. The key which authenticated the sector is known,
+// a key not known is taken from the dump and so not compared.
+func unmaskTrailer(current, dump *[MifareClassicBlockSize]byte, keyNumber MifareClassicKeyType, key MifareClassicKey) {
+	copy(current[0:6], dump[0:6])
+	if keyNumber == MifareClassicKeyA {
+		copy(current[0:6], key)
+	}
+	if access, err := DecodeMifareClassicAccessBits(current[6:10]); err == nil && access.KeyBReadable() {
+		return
+	}
+	copy(current[10:16], dump[10:16])
+	if keyNumber == MifareClassicKeyB {
+		copy(current[10:16], key)
+	}
+}
+
+// recover selects the card again after it refused a command and halted.
+// Errors of the PN532 or the bus are returned. The lock of the device must
+// be held.
+func (m *MifareClassic) recover(err error) error {
+	var status StatusError
+	if !errors.As(err, &status) {
+		return err
+	}
+	return m.reselect()
+}
@@ -0,0 +1,280 @@
+package pn532
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDump is returned for a dump file not holding a whole card.
+var ErrInvalidDump = errors.New("invalid MIFARE Classic dump")
+
+// The .eml line of a block which could not be read
+const emlUnreadable = "--------------------------------"
+
+// mifareClassicGeometryFromBlocks returns the geometry of a card with the
+// given number of blocks.
+func mifareClassicGeometryFromBlocks(blocks int) (MifareClassicGeometry, error) {
+	for _, geometry := range []MifareClassicGeometry{MifareClassicMini, MifareClassic1K, MifareClassic2K, MifareClassic4K} {
+		if int(geometry.TotalBlocks()) == blocks {
+			return geometry, nil
+		}
+	}
+	return MifareClassicGeometry{}, ErrInvalidDump
+}
+
+// fillFromBlocks restores the card identification from the manufacturer
+// block and the keys from the sector trailers, as the .mfd and .eml formats
+// only hold the blocks. A card reads key A and often key B as zeros, so only
+// keys other than zero are taken.
+func (d *MifareClassicDump) fillFromBlocks() {
+	if d.Readable[0] {
+		// a 7 byte UID has no BCC
+		manufacturer, err := ParseMifareClassicManufacturer(d.Blocks[0][:], 4)
+		if err == ErrInvalidBCC {
+			manufacturer, err = ParseMifareClassicManufacturer(d.Blocks[0][:], 7)
+		}
+		if err == nil {
+			d.UID = manufacturer.UID
+			d.SAK = manufacturer.SAK
+			d.ATQA = manufacturer.ATQA
+		}
+	}
+	for sector := uint8(0); sector < d.Geometry.Sectors; sector++ {
+		trailer := d.Geometry.TrailerBlock(sector)
+		if !d.Readable[trailer] {
+			continue
+		}
+		if key := d.Blocks[trailer][0:6]; !isZero(key) {
+			d.Keys[sector].KeyA = append(MifareClassicKey{}, key...)
+		}
+		if key := d.Blocks[trailer][10:16]; !isZero(key) {
+			d.Keys[sector].KeyB = append(MifareClassicKey{}, key...)
+		}
+	}
+}
+
+// isZero reports whether all bytes are zero.
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteMFD writes the dump in the raw binary .mfd (.bin) layout, all blocks
+// one after the other. Unreadable blocks are written as zeros.
+func (d *MifareClassicDump) WriteMFD(w io.Writer) error {
+	for i := range d.Blocks {
+		if _, err := w.Write(d.Blocks[i][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMifareClassicMFD reads a dump in the raw binary .mfd (.bin) layout. The
+// card size is derived from the file size, all blocks are readable.
+func ReadMifareClassicMFD(r io.Reader) (*MifareClassicDump, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%MifareClassicBlockSize != 0 {
+		return nil, ErrInvalidDump
+	}
+	geometry, err := mifareClassicGeometryFromBlocks(len(data) / MifareClassicBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	dump := newMifareClassicDump(geometry)
+	for i := range dump.Blocks {
+		copy(dump.Blocks[i][:], data[i*MifareClassicBlockSize:])
+		dump.Readable[i] = true
+	}
+	dump.fillFromBlocks()
+	return dump, nil
+}
+
+// WriteEML writes the dump in the Proxmark .eml text format, one block per
+// line in hex. Unreadable blocks are written as dashes.
+func (d *MifareClassicDump) WriteEML(w io.Writer) error {
+	for i := range d.Blocks {
+		line := emlUnreadable
+		if d.Readable[i] {
+			line = strings.ToUpper(hex.EncodeToString(d.Blocks[i][:]))
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMifareClassicEML reads a dump in the Proxmark .eml text format.
+func ReadMifareClassicEML(r io.Reader) (*MifareClassicDump, error) {
+	var blocks [][MifareClassicBlockSize]byte
+	var readable []bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var block [MifareClassicBlockSize]byte
+		if line == emlUnreadable {
+			blocks, readable = append(blocks, block), append(readable, false)
+			continue
+		}
+		if len(line) != 2*len(block) {
+			return nil, ErrInvalidDump
+		}
+		if _, err := hex.Decode(block[:], []byte(line)); err != nil {
+			return nil, ErrInvalidDump
+		}
+		blocks, readable = append(blocks, block), append(readable, true)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	geometry, err := mifareClassicGeometryFromBlocks(len(blocks))
+	if err != nil {
+		return nil, err
+	}
+	dump := newMifareClassicDump(geometry)
+	copy(dump.Blocks, blocks)
+	copy(dump.Readable, readable)
+	dump.fillFromBlocks()
+	return dump, nil
+}
+
+// mifareClassicJSON is the JSON layout, following the Proxmark JSON dumps.
+// Unreadable blocks and unknown keys are left out.
+type mifareClassicJSON struct {
+	Created  string `json:"Created"`
+	FileType string `json:"FileType"`
+	Card     struct {
+		UID  string `json:"UID"`
+		ATQA string `json:"ATQA"`
+		SAK  string `json:"SAK"`
+	} `json:"Card"`
+	Sectors    uint8                        `json:"Sectors"`
+	Blocks     map[string]string            `json:"blocks"`
+	SectorKeys map[string]map[string]string `json:"SectorKeys"`
+}
+
+// WriteJSON writes the dump as JSON, including which blocks could be read
+// and which keys worked.
+func (d *MifareClassicDump) WriteJSON(w io.Writer) error {
+	file := mifareClassicJSON{
+		Created:    "tinygo-pn532",
+		FileType:   "mfcard",
+		Sectors:    d.Geometry.Sectors,
+		Blocks:     make(map[string]string),
+		SectorKeys: make(map[string]map[string]string),
+	}
+	file.Card.UID = strings.ToUpper(hex.EncodeToString(d.UID))
+	file.Card.ATQA = strings.ToUpper(hex.EncodeToString([]byte{byte(d.ATQA), byte(d.ATQA >> 8)}))
+	file.Card.SAK = strings.ToUpper(hex.EncodeToString([]byte{d.SAK}))
+	for i := range d.Blocks {
+		if d.Readable[i] {
+			file.Blocks[strconv.Itoa(i)] = strings.ToUpper(hex.EncodeToString(d.Blocks[i][:]))
+		}
+	}
+	for sector, keys := range d.Keys {
+		entry := make(map[string]string)
+		if keys.KeyA != nil {
+			entry["KeyA"] = strings.ToUpper(hex.EncodeToString(keys.KeyA))
+		}
+		if keys.KeyB != nil {
+			entry["KeyB"] = strings.ToUpper(hex.EncodeToString(keys.KeyB))
+		}
+		if len(entry) > 0 {
+			file.SectorKeys[strconv.Itoa(sector)] = entry
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&file)
+}
+
+// ReadMifareClassicJSON reads a dump written by WriteJSON. Without the
+// sectors field, the card size is derived from the highest block.
+func ReadMifareClassicJSON(r io.Reader) (*MifareClassicDump, error) {
+	var file mifareClassicJSON
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	geometry := MifareClassicGeometry{Sectors: file.Sectors}
+	if file.Sectors == 0 {
+		highest := 0
+		for number := range file.Blocks {
+			block, err := strconv.Atoi(number)
+			if err != nil {
+				return nil, ErrInvalidDump
+			}
+			if block > highest {
+				highest = block
+			}
+		}
+		geometry = MifareClassicMini
+		for _, g := range []MifareClassicGeometry{MifareClassic1K, MifareClassic2K, MifareClassic4K} {
+			if !geometry.Contains(uint32(highest)) {
+				geometry = g
+			}
+		}
+	}
+	// only the sizes of existing cards
+	if _, err := mifareClassicGeometryFromBlocks(int(geometry.TotalBlocks())); err != nil {
+		return nil, err
+	}
+	dump := newMifareClassicDump(geometry)
+	var err error
+	if dump.UID, err = hex.DecodeString(file.Card.UID); err != nil {
+		return nil, ErrInvalidDump
+	}
+	if atqa, err := hex.DecodeString(file.Card.ATQA); err == nil && len(atqa) == 2 {
+		dump.ATQA = uint16(atqa[1])<<8 | uint16(atqa[0])
+	}
+	if sak, err := hex.DecodeString(file.Card.SAK); err == nil && len(sak) == 1 {
+		dump.SAK = sak[0]
+	}
+	for number, content := range file.Blocks {
+		block, err := strconv.Atoi(number)
+		if err != nil || block < 0 || block >= len(dump.Blocks) {
+			return nil, ErrInvalidDump
+		}
+		if len(content) != 2*MifareClassicBlockSize {
+			return nil, ErrInvalidDump
+		}
+		if _, err := hex.Decode(dump.Blocks[block][:], []byte(content)); err != nil {
+			return nil, ErrInvalidDump
+		}
+		dump.Readable[block] = true
+	}
+	for number, keys := range file.SectorKeys {
+		sector, err := strconv.Atoi(number)
+		if err != nil || sector < 0 || sector >= len(dump.Keys) {
+			return nil, ErrInvalidDump
+		}
+		for name, key := range keys {
+			decoded, err := hex.DecodeString(key)
+			if err != nil || len(decoded) != 6 {
+				return nil, ErrInvalidDump
+			}
+			switch name {
+			case "KeyA":
+				dump.Keys[sector].KeyA = decoded
+			case "KeyB":
+				dump.Keys[sector].KeyB = decoded
+			}
+		}
+	}
+	return dump, nil
+}
@@ -0,0 +1,96 @@
+package pn532
+
+// MifareClassicGeometry describes the memory layout of a MIFARE Classic card.
+// The first 32 sectors hold 4 blocks each, the sectors above hold 16 blocks
+// each, which is only the case for the 4K card.
+type MifareClassicGeometry struct {
+	Sectors uint8 // The number of sectors of the card
+}
+
+// The geometries of the MIFARE Classic family
+var (
+	MifareClassicMini = MifareClassicGeometry{Sectors: 5}  // 320 bytes
+	MifareClassic1K   = MifareClassicGeometry{Sectors: 16} // 1024 bytes
+	MifareClassic2K   = MifareClassicGeometry{Sectors: 32} // 2048 bytes
+	MifareClassic4K   = MifareClassicGeometry{Sectors: 40} // 4096 bytes
+)
+
+const (
+	mifareClassicSmallSectors   = 32 // Sectors holding 4 blocks
+	mifareClassicSmallSectorLen = 4
+	mifareClassicLargeSectorLen = 16
+	mifareClassicMaxSectors     = 40 // Sectors of the 4K card
+)
+
+// MifareClassicGeometryFromSAK returns the geometry of the card announced by
+// the SEL_RES (SAK). It returns false if the SAK does not belong to a MIFARE
+// Classic card. See NXP AN10833 MIFARE type identification procedure.
+func MifareClassicGeometryFromSAK(sak uint8) (MifareClassicGeometry, bool) {
+	switch sak {
+	case 0x09:
+		return MifareClassicMini, true
+	case 0x08, 0x28, 0x88:
+		return MifareClassic1K, true
+	case 0x19:
+		return MifareClassic2K, true
+	case 0x18, 0x38, 0x98, 0xB8:
+		return MifareClassic4K, true
+	}
+	return MifareClassicGeometry{}, false
+}
+
+// TotalBlocks returns the number of blocks of the card.
+func (g MifareClassicGeometry) TotalBlocks() uint32 {
+	if g.Sectors <= mifareClassicSmallSectors {
+		return uint32(g.Sectors) * mifareClassicSmallSectorLen
+	}
+	return mifareClassicSmallSectors*mifareClassicSmallSectorLen +
+		uint32(g.Sectors-mifareClassicSmallSectors)*mifareClassicLargeSectorLen
+}
+
+// BlocksInSector returns the number of blocks of the sector, including the
+// sector trailer.
+func (g MifareClassicGeometry) BlocksInSector(sector uint8) uint32 {
+	if sector < mifareClassicSmallSectors {
+		return mifareClassicSmallSectorLen
+	}
+	return mifareClassicLargeSectorLen
+}
+
+// FirstBlock returns the first block of the sector.
+func (g MifareClassicGeometry) FirstBlock(sector uint8) uint32 {
+	if sector < mifareClassicSmallSectors {
+		return uint32(sector) * mifareClassicSmallSectorLen
+	}
+	return mifareClassicSmallSectors*mifareClassicSmallSectorLen +
+		uint32(sector-mifareClassicSmallSectors)*mifareClassicLargeSectorLen
+}
+
+// TrailerBlock returns the sector trailer, the last block of the sector.
+func (g MifareClassicGeometry) TrailerBlock(sector uint8) uint32 {
+	return g.FirstBlock(sector) + g.BlocksInSector(sector) - 1
+}
+
+// SectorOfBlock returns the sector the block belongs to.
+func (g MifareClassicGeometry) SectorOfBlock(block uint32) uint8 {
+	const smallBlocks = mifareClassicSmallSectors * mifareClassicSmallSectorLen
+	if block < smallBlocks {
+		return uint8(block / mifareClassicSmallSectorLen)
+	}
+	return uint8(mifareClassicSmallSectors + (block-smallBlocks)/mifareClassicLargeSectorLen)
+}
+
+// IsFirstBlock reports whether the block is the first block of its sector.
+func (g MifareClassicGeometry) IsFirstBlock(block uint32) bool {
+	return g.FirstBlock(g.SectorOfBlock(block)) == block
+}
+
+// IsTrailerBlock reports whether the block is the trailer of its sector.
+func (g MifareClassicGeometry) IsTrailerBlock(block uint32) bool {
+	return g.TrailerBlock(g.SectorOfBlock(block)) == block
+}
+
+// Contains reports whether the block exists on the card.
+func (g MifareClassicGeometry) Contains(block uint32) bool {
+	return block < g.TotalBlocks()
+}
@@ -0,0 +1,107 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MifareClassicDictionary is a list of well known keys: the transport key,
+// the MAD and NFC Forum keys and keys found on common deployments.
+var MifareClassicDictionary = []MifareClassicKey{
+	{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, // transport key
+	{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}, // MAD key A
+	{0xD3, 0xF7, 0xD3, 0xF7, 0xD3, 0xF7}, // NFC Forum key A
+	{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+	{0xB0, 0xB1, 0xB2, 0xB3, 0xB4, 0xB5},
+	{0x4D, 0x3A, 0x99, 0xC3, 0x51, 0xDD},
+	{0x1A, 0x98, 0x2C, 0x7E, 0x45, 0x9A},
+	{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+	{0x71, 0x4C, 0x5C, 0x88, 0x6E, 0x97},
+	{0x58, 0x7E, 0xE5, 0xF9, 0x35, 0x0F},
+	{0xA0, 0x47, 0x8C, 0xC3, 0x90, 0x91},
+	{0x53, 0x3C, 0xB6, 0xC7, 0x23, 0xF6},
+	{0x8F, 0xD0, 0xA4, 0xF2, 0x56, 0xE9},
+}
+
+// MifareClassicSectorKeys holds the keys of a sector, nil if unknown.
+type MifareClassicSectorKeys struct {
+	KeyA MifareClassicKey
+	KeyB MifareClassicKey
+}
+
+// ErrNoKeyFound is returned by RecoverKeys if no key of the dictionary
+// authenticates any sector of the card.
+var ErrNoKeyFound = errors.New("no key of the dictionary matches")
+
+// SetSectorKey sets the key used to authenticate the sector. A nil key
+// selects the default key again.
+func (m *MifareClassic) SetSectorKey(sector uint8, keyNumber MifareClassicKeyType, key MifareClassicKey) {
+	if int(sector) < len(m.sectorKeys) {
+		m.sectorKeys[sector][keyNumber] = key
+	}
+}
+
+// SectorKey returns the key used to authenticate the sector.
+func (m *MifareClassic) SectorKey(sector uint8, keyNumber MifareClassicKeyType) MifareClassicKey {
+	if int(sector) < len(m.sectorKeys) && m.sectorKeys[sector][keyNumber] != nil {
+		return m.sectorKeys[sector][keyNumber]
+	}
+	return m.keys[keyNumber]
+}
+
+// RecoverKeys tries the keys of the dictionary on every sector of the card and
+// stores the keys found, see SectorKey. The returned map holds the keys per
+// sector, a nil key was not found. A failed authentication halts the card, so
+// it is selected again after every miss, the card must stay in the field.
+func (m *MifareClassic) RecoverKeys(uid []byte, dictionary []MifareClassicKey) ([]MifareClassicSectorKeys, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	keyMap := make([]MifareClassicSectorKeys, m.geometry.Sectors)
+	found := false
+	for sector := uint8(0); sector < m.geometry.Sectors; sector++ {
+		block := m.geometry.TrailerBlock(sector)
+		for _, keyNumber := range []MifareClassicKeyType{MifareClassicKeyA, MifareClassicKeyB} {
+			key, err := m.tryKeys(uid, block, keyNumber, dictionary)
+			if err != nil {
+				return keyMap, err
+			}
+			if key == nil {
+				continue
+			}
+			found = true
+			m.SetSectorKey(sector, keyNumber, key)
+			if keyNumber == MifareClassicKeyA {
+				keyMap[sector].KeyA = key
+			} else {
+				keyMap[sector].KeyB = key
+			}
+		}
+	}
+	if !found {
+		return keyMap, ErrNoKeyFound
+	}
+	return keyMap, nil
+}
+
+// tryKeys returns the first key of the dictionary authenticating the block,
+// nil if none does. The lock of the device must be held.
+func (m *MifareClassic) tryKeys(uid []byte, block uint32, keyNumber MifareClassicKeyType, dictionary []MifareClassicKey) (MifareClassicKey, error) {
+	for _, key := range dictionary {
+		err := m.authenticate(uid, block, keyNumber, key)
+		if err == nil {
+			return key, nil
+		}
+		if err := m.recover(err); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// reselect activates the card again after a failed authentication. The lock
+// of the device must be held.
+func (m *MifareClassic) reselect() error {
+	_, err := m.dev.readPassiveTarget(context.Background(), MIFARE_ISO14443A, 100*time.Millisecond)
+	return err
+}
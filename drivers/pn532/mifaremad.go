@@ -0,0 +1,317 @@
+package pn532
+
+import "errors"
+
+// MifareApplicationID identifies the application using a sector, as listed
+// in the MIFARE Application Directory. The upper byte is the function
+// cluster, the lower byte the application code. See NXP AN10787.
+type MifareApplicationID uint16
+
+// Administration codes and well known applications
+const (
+	MADFree           MifareApplicationID = 0x0000 // Sector is free
+	MADDefect         MifareApplicationID = 0x0001 // Sector is defect
+	MADReserved       MifareApplicationID = 0x0002 // Sector is reserved
+	MADAdditionalInfo MifareApplicationID = 0x0003 // Sector holds additional directory information
+	MADCardHolder     MifareApplicationID = 0x0004 // Sector holds card holder information
+	MADNotApplicable  MifareApplicationID = 0x0005 // Sector does not exist on the card
+	MADNDEF           MifareApplicationID = 0xE103 // NFC Forum NDEF message, stored as 03 E1
+)
+
+const (
+	// The sector of the MAD2 extension on cards with more than 16 sectors
+	madSector2 = 16
+	// The sectors covered by the MAD1 and the MAD2
+	madSectors1 = 16
+	madSectors2 = 40
+	// GPB bits of the MAD sector trailer
+	madAvailable        = 0x80 // DA: the MAD is available
+	madMultiApplication = 0x40 // MA: multi application card
+	madVersionMask      = 0x03 // ADV: MAD version
+)
+
+var (
+	ErrNoMAD       = errors.New("the card holds no MIFARE Application Directory")
+	ErrInvalidMAD  = errors.New("invalid MIFARE Application Directory")
+	ErrMADNoSector = errors.New("not enough free sectors in the MIFARE Application Directory")
+)
+
+// MADKeyA is the public key A of the MAD sectors.
+var MADKeyA = MifareClassicKey{0xA0, 0xA1, 0xA2, 0xA3, 0xA4, 0xA5}
+
+// MADAccessBits are the access bits of the MAD sectors: the directory is
+// readable with key A and writable with key B only (78 77 88).
+func MADAccessBits(version uint8) MifareClassicAccessBits {
+	return MifareClassicAccessBits{
+		Conditions: [4]uint8{
+			MifareClassicDataWriteB,
+			MifareClassicDataWriteB,
+			MifareClassicDataWriteB,
+			MifareClassicTrailerKeyB,
+		},
+		GPB: madAvailable | madMultiApplication | version&madVersionMask,
+	}
+}
+
+// MifareApplicationDirectory lists the application of every sector.
+type MifareApplicationDirectory struct {
+	Version uint8 // 1 or 2, version 2 is needed for cards with more than 16 sectors
+	// Publisher is the sector holding the card publisher information, 0 if
+	// there is none.
+	Publisher uint8
+	// AIDs holds the application of each sector, indexed by sector. The
+	// entries of the MAD sectors themselves are unused.
+	AIDs []MifareApplicationID
+}
+
+// NewMifareApplicationDirectory returns an empty directory for the card.
+func NewMifareApplicationDirectory(geometry MifareClassicGeometry) *MifareApplicationDirectory {
+	mad := &MifareApplicationDirectory{Version: 1, AIDs: make([]MifareApplicationID, geometry.Sectors)}
+	if geometry.Sectors > madSectors1 {
+		mad.Version = 2
+	}
+	return mad
+}
+
+// isMADSector reports whether the sector holds the directory.
+func (d *MifareApplicationDirectory) isMADSector(sector uint8) bool {
+	return sector == 0 || (d.Version == 2 && sector == madSector2)
+}
+
+// Sectors returns the sectors allocated to the application.
+func (d *MifareApplicationDirectory) Sectors(aid MifareApplicationID) []uint8 {
+	var sectors []uint8
+	for sector, id := range d.AIDs {
+		if id == aid && !d.isMADSector(uint8(sector)) {
+			sectors = append(sectors, uint8(sector))
+		}
+	}
+	return sectors
+}
+
+// Allocate assigns count free sectors to the application and returns them.
+// Nothing is allocated if there are not enough free sectors.
+func (d *MifareApplicationDirectory) Allocate(aid MifareApplicationID, count int) ([]uint8, error) {
+	sectors := d.Sectors(MADFree)
+	if len(sectors) < count {
+		return nil, ErrMADNoSector
+	}
+	sectors = sectors[:count]
+	for _, sector := range sectors {
+		d.AIDs[sector] = aid
+	}
+	return sectors, nil
+}
+
+// madCRC computes the CRC-8 of the directory: polynomial 0x1D, preset 0xC7.
+func madCRC(data []byte) byte {
+	crc := byte(0xC7)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x1D
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// encodeMAD composes the CRC, the info byte and the AIDs of the sectors,
+// LSB first, as stored in the data blocks of a MAD sector.
+func encodeMAD(publisher uint8, aids []MifareApplicationID) []byte {
+	data := make([]byte, 2+2*len(aids))
+	data[1] = publisher & 0x3F
+	for i, aid := range aids {
+		data[2+2*i] = byte(aid)
+		data[3+2*i] = byte(aid >> 8)
+	}
+	data[0] = madCRC(data[1:])
+	return data
+}
+
+// decodeMAD verifies the CRC and returns the info byte and the AIDs.
+func decodeMAD(data []byte) (uint8, []MifareApplicationID, error) {
+	if madCRC(data[1:]) != data[0] {
+		return 0, nil, ErrInvalidMAD
+	}
+	aids := make([]MifareApplicationID, (len(data)-2)/2)
+	for i := range aids {
+		aids[i] = MifareApplicationID(data[2+2*i]) | MifareApplicationID(data[3+2*i])<<8
+	}
+	return data[1] & 0x3F, aids, nil
+}
+
+// ReadMAD reads the directory from sector 0 and, for version 2, from sector
+// 16. The sectors are authenticated with the public MAD key A.
+func (m *MifareClassic) ReadMAD(uid []byte) (*MifareApplicationDirectory, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	mad := NewMifareApplicationDirectory(m.geometry)
+	if err := m.authenticateMAD(uid, 0); err != nil {
+		return nil, err
+	}
+	trailer, err := m.readDataBlock(uint8(m.geometry.TrailerBlock(0)))
+	if err != nil {
+		return nil, err
+	}
+	gpb := trailer[9]
+	if gpb&madAvailable == 0 {
+		return nil, ErrNoMAD
+	}
+	mad.Version = gpb & madVersionMask
+	if mad.Version != 1 && mad.Version != 2 {
+		return nil, ErrInvalidMAD
+	}
+	data, err := m.readMADSector(0)
+	if err != nil {
+		return nil, err
+	}
+	publisher, aids, err := decodeMAD(data)
+	if err != nil {
+		return nil, err
+	}
+	mad.Publisher = publisher
+	copy(mad.AIDs[1:], aids)
+	if mad.Version < 2 || m.geometry.Sectors <= madSectors1 {
+		return mad, nil
+	}
+	if err := m.authenticateMAD(uid, madSector2); err != nil {
+		return nil, err
+	}
+	if data, err = m.readMADSector(madSector2); err != nil {
+		return nil, err
+	}
+	// the MAD2 publisher pointer overrides the one of the MAD1
+	if publisher, aids, err = decodeMAD(data); err != nil {
+		return nil, err
+	}
+	if publisher != 0 {
+		mad.Publisher = publisher
+	}
+	copy(mad.AIDs[madSector2+1:], aids)
+	return mad, nil
+}
+
+// authenticateMAD authenticates a MAD sector with the public MAD key A. A
+// sector refusing the key holds no MAD. The lock of the device must be held.
+func (m *MifareClassic) authenticateMAD(uid []byte, sector uint8) error {
+	err := m.authenticate(uid, m.geometry.FirstBlock(sector), MifareClassicKeyA, MADKeyA)
+	if err == nil {
+		return nil
+	}
+	if err := m.recover(err); err != nil {
+		return err
+	}
+	return ErrNoMAD
+}
+
+// readMADSector reads the data blocks of a MAD sector, skipping the
+// manufacturer block of sector 0. The lock of the device must be held.
+func (m *MifareClassic) readMADSector(sector uint8) ([]byte, error) {
+	first := m.geometry.FirstBlock(sector)
+	if sector == 0 {
+		first = 1
+	}
+	var data []byte
+	for block := first; block < m.geometry.TrailerBlock(sector); block++ {
+		content, err := m.readDataBlock(uint8(block))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, content...)
+	}
+	return data, nil
+}
+
+// WriteMAD writes the directory and the trailers of the MAD sectors, with
+// the public MAD key A and the given secret key B. The sectors are
+// authenticated with the keys of the key store, which is updated to the new
+// keys afterwards.
+func (m *MifareClassic) WriteMAD(uid []byte, mad *MifareApplicationDirectory, keyB MifareClassicKey) error {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	if len(mad.AIDs) != int(m.geometry.Sectors) {
+		return errors.New("the directory does not fit the card")
+	}
+	switch {
+	case mad.Version != 1 && mad.Version != 2:
+		return ErrInvalidMAD
+	case mad.Version == 1 && m.geometry.Sectors > madSectors1:
+		return errors.New("the card needs a MAD version 2")
+	case mad.Version == 2 && m.geometry.Sectors <= madSectors1:
+		// there is no sector 16 for the second directory
+		return errors.New("the card needs a MAD version 1")
+	}
+	// sectors not present on the card are marked as not applicable
+	aids := make([]MifareApplicationID, madSectors2)
+	copy(aids, mad.AIDs)
+	for sector := m.geometry.Sectors; sector < madSectors2; sector++ {
+		aids[sector] = MADNotApplicable
+	}
+	if err := m.writeMADSector(uid, 0, mad.Version, encodeMAD(mad.Publisher, aids[1:madSectors1]), keyB); err != nil {
+		return err
+	}
+	if mad.Version < 2 {
+		return nil
+	}
+	return m.writeMADSector(uid, madSector2, mad.Version, encodeMAD(mad.Publisher, aids[madSector2+1:]), keyB)
+}
+
+// writeMADSector writes the data blocks and the trailer of a MAD sector. The
+// lock of the device must be held.
+func (m *MifareClassic) writeMADSector(uid []byte, sector uint8, version uint8, data []byte, keyB MifareClassicKey) error {
+	trailer := MifareClassicTrailer{KeyA: MADKeyA, Access: MADAccessBits(version), KeyB: keyB}
+	trailerBlock, err := trailer.Build()
+	if err != nil {
+		return err
+	}
+	if err := m.authenticateSector(uid, sector); err != nil {
+		return err
+	}
+	first := m.geometry.FirstBlock(sector)
+	if sector == 0 {
+		first = 1
+	}
+	for block := first; len(data) > 0; block++ {
+		if err := m.writeDataBlock(uint8(block), data[:MifareClassicBlockSize]); err != nil {
+			return err
+		}
+		data = data[MifareClassicBlockSize:]
+	}
+	if err := m.writeDataBlock(uint8(m.geometry.TrailerBlock(sector)), trailerBlock[:]); err != nil {
+		return err
+	}
+	m.SetSectorKey(sector, MifareClassicKeyA, MADKeyA)
+	m.SetSectorKey(sector, MifareClassicKeyB, keyB)
+	return nil
+}
+
+// authenticateSector authenticates the sector for writing with the keys of
+// the key store. Key A is used while key B is readable, as a readable key B
+// can not serve for authentication, otherwise key B. The lock of the device
+// must be held.
+func (m *MifareClassic) authenticateSector(uid []byte, sector uint8) error {
+	block := m.geometry.FirstBlock(sector)
+	keyB := m.SectorKey(sector, MifareClassicKeyB)
+	if err := m.authenticate(uid, block, MifareClassicKeyA, m.SectorKey(sector, MifareClassicKeyA)); err != nil {
+		if err := m.recover(err); err != nil {
+			return err
+		}
+		return m.authenticate(uid, block, MifareClassicKeyB, keyB)
+	}
+	trailer, err := m.readDataBlock(uint8(m.geometry.TrailerBlock(sector)))
+	if err != nil {
+		return err
+	}
+	access, err := DecodeMifareClassicAccessBits(trailer[6:10])
+	if err != nil {
+		return err
+	}
+	if access.KeyBReadable() {
+		return nil
+	}
+	return m.authenticate(uid, block, MifareClassicKeyB, keyB)
+}
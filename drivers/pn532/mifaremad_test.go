@@ -0,0 +1,111 @@
+package pn532
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// Data blocks 1 and 2 of MAD sectors.
+var madVectors = []struct {
+	name      string
+	publisher uint8
+	aids      []MifareApplicationID
+	data      []byte
+}{
+	{
+		// The example of NXP AN10787 MIFARE Application Directory
+		name:      "AN10787 example",
+		publisher: 0x01,
+		aids: []MifareApplicationID{
+			0x0801, 0x0801, 0x0801, MADFree, MADFree, MADFree, MADCardHolder,
+			0x1003, 0x1003, 0x1002, 0x1002, MADFree, MADFree, MADFree, 0x3011,
+		},
+		data: []byte{
+			0x89, 0x01, 0x01, 0x08, 0x01, 0x08, 0x01, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, 0x00,
+			0x03, 0x10, 0x03, 0x10, 0x02, 0x10, 0x02, 0x10, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x11, 0x30,
+		},
+	},
+	{
+		// A MIFARE Classic 1K formatted as NFC Forum tag, see NXP AN1304
+		name:      "NDEF",
+		publisher: 0x01,
+		aids: []MifareApplicationID{
+			MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF,
+			MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF, MADNDEF,
+		},
+		data: []byte{
+			0x14, 0x01, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1,
+			0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1, 0x03, 0xE1,
+		},
+	},
+}
+
+func TestEncodeMAD(t *testing.T) {
+	for _, vector := range madVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			if got := encodeMAD(vector.publisher, vector.aids); !bytes.Equal(got, vector.data) {
+				t.Errorf("encodeMAD = % X, want % X", got, vector.data)
+			}
+		})
+	}
+}
+
+func TestDecodeMAD(t *testing.T) {
+	for _, vector := range madVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			publisher, aids, err := decodeMAD(vector.data)
+			if err != nil {
+				t.Fatalf("decodeMAD: %v", err)
+			}
+			if publisher != vector.publisher {
+				t.Errorf("publisher %d, want %d", publisher, vector.publisher)
+			}
+			if !reflect.DeepEqual(aids, vector.aids) {
+				t.Errorf("AIDs %04X, want %04X", aids, vector.aids)
+			}
+		})
+	}
+	corrupt := append([]byte{}, madVectors[0].data...)
+	corrupt[14] ^= 0x01
+	if _, _, err := decodeMAD(corrupt); err != ErrInvalidMAD {
+		t.Errorf("decodeMAD with wrong CRC: %v, want ErrInvalidMAD", err)
+	}
+}
+
+func TestMADAccessBits(t *testing.T) {
+	tests := []struct {
+		version uint8
+		want    [4]byte
+	}{
+		{1, [4]byte{0x78, 0x77, 0x88, 0xC1}},
+		{2, [4]byte{0x78, 0x77, 0x88, 0xC2}},
+	}
+	for _, test := range tests {
+		if got := MADAccessBits(test.version).Encode(); got != test.want {
+			t.Errorf("MADAccessBits(%d) = %X, want %X", test.version, got, test.want)
+		}
+	}
+}
+
+func TestMifareApplicationDirectoryAllocate(t *testing.T) {
+	mad := NewMifareApplicationDirectory(MifareClassic4K)
+	if mad.Version != 2 {
+		t.Fatalf("version %d for 40 sectors, want 2", mad.Version)
+	}
+	sectors, err := mad.Allocate(MADNDEF, 16)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	// sector 16 holds the MAD version 2
+	want := []uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 17}
+	if !bytes.Equal(sectors, want) {
+		t.Errorf("Allocate = %v, want %v", sectors, want)
+	}
+	if _, err := mad.Allocate(MADNDEF, 23); err != ErrMADNoSector {
+		t.Errorf("Allocate beyond the free sectors: %v, want ErrMADNoSector", err)
+	}
+	if got := len(mad.Sectors(MADFree)); got != 22 {
+		t.Errorf("%d free sectors after a failed Allocate, want 22", got)
+	}
+}
@@ -0,0 +1,287 @@
+package pn532
+
+import (
+	"errors"
+	"time"
+)
+
+// MifareClassicMagic is the generation of a magic card, a clone of the
+// MIFARE Classic with a writable manufacturer block.
+type MifareClassicMagic uint8
+
+const (
+	MifareClassicMagicNone    MifareClassicMagic = iota // A genuine card, block 0 is read-only
+	MifareClassicMagicGen1a                             // Block 0 is written after a backdoor unlock
+	MifareClassicMagicGen2                              // Block 0 is written like any other block (CUID)
+	MifareClassicMagicUnknown                           // No Gen1a card, whether it is a Gen2 card is not known
+)
+
+func (g MifareClassicMagic) String() string {
+	switch g {
+	case MifareClassicMagicGen1a:
+		return "Gen1a"
+	case MifareClassicMagicGen2:
+		return "Gen2"
+	case MifareClassicMagicUnknown:
+		return "unknown"
+	}
+	return "none"
+}
+
+// ErrInvalidBCC is returned for a manufacturer block whose BCC does not
+// match the UID. Such a card does not answer the anticollision anymore.
+var ErrInvalidBCC = errors.New("the BCC does not match the UID")
+
+// ErrNoMagicCard is returned if the card does not allow to write block 0.
+var ErrNoMagicCard = errors.New("the card is no magic card")
+
+// The answer of the card acknowledging a frame, 4 bits only
+const mifareACK = 0x0A
+
+// Backdoor commands of Gen1a cards, sent without CRC
+const (
+	mifareGen1aUnlock1 = 0x40 // Sent as 7 bit short frame
+	mifareGen1aUnlock2 = 0x43
+)
+
+// MifareClassicManufacturer is the content of block 0 of a card with a 4 or
+// 7 byte UID.
+type MifareClassicManufacturer struct {
+	UID  []byte // 4 or 7 bytes
+	SAK  uint8
+	ATQA uint16
+	Data []byte // Manufacturer data, 8 bytes after a 4 byte UID, 6 bytes after a 7 byte UID
+}
+
+// MifareClassicBCC returns the block check character of the UID, the XOR of
+// its bytes.
+func MifareClassicBCC(uid []byte) byte {
+	var bcc byte
+	for _, b := range uid {
+		bcc ^= b
+	}
+	return bcc
+}
+
+// Build composes block 0. A 4 byte UID is followed by its BCC, the SAK, the
+// ATQA LSB first and the manufacturer data. A 7 byte UID has no BCC, it is
+// followed by the SAK, the ATQA and the manufacturer data.
+func (m *MifareClassicManufacturer) Build() ([MifareClassicBlockSize]byte, error) {
+	var block [MifareClassicBlockSize]byte
+	offset := len(m.UID)
+	switch len(m.UID) {
+	case 4:
+		block[4] = MifareClassicBCC(m.UID)
+		offset++
+	case 7:
+	default:
+		return block, errors.New("the UID needs 4 or 7 bytes")
+	}
+	copy(block[0:], m.UID)
+	block[offset] = m.SAK
+	block[offset+1] = byte(m.ATQA)
+	block[offset+2] = byte(m.ATQA >> 8)
+	if len(m.Data) > MifareClassicBlockSize-offset-3 {
+		return block, errors.New("the manufacturer data exceeds block 0")
+	}
+	copy(block[offset+3:], m.Data)
+	return block, nil
+}
+
+// ParseMifareClassicManufacturer decodes block 0 of a card with a UID of the
+// given length, 4 or 7 bytes. It returns ErrInvalidBCC if the BCC does not
+// match a 4 byte UID.
+func ParseMifareClassicManufacturer(block []byte, uidLength int) (MifareClassicManufacturer, error) {
+	manufacturer := MifareClassicManufacturer{}
+	if len(block) != MifareClassicBlockSize {
+		return manufacturer, errors.New("block 0 needs 16 bytes")
+	}
+	offset := uidLength
+	switch uidLength {
+	case 4:
+		if MifareClassicBCC(block[0:4]) != block[4] {
+			return manufacturer, ErrInvalidBCC
+		}
+		offset++
+	case 7:
+	default:
+		return manufacturer, errors.New("the UID needs 4 or 7 bytes")
+	}
+	manufacturer.UID = append([]byte{}, block[0:uidLength]...)
+	manufacturer.SAK = block[offset]
+	manufacturer.ATQA = uint16(block[offset+2])<<8 | uint16(block[offset+1])
+	manufacturer.Data = append([]byte{}, block[offset+3:]...)
+	return manufacturer, nil
+}
+
+// DetectMagic reports whether a Gen1a magic card is in the field, using its
+// backdoor commands without writing. Any other card is reported as
+// MifareClassicMagicUnknown: a Gen2 card behaves like a genuine card until
+// block 0 is written, only ProbeGen2 confirms it by writing block 0.
+func (m *MifareClassic) DetectMagic(uid []byte) (MifareClassicMagic, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	gen1a, err := m.detectGen1a()
+	if err != nil {
+		return MifareClassicMagicNone, err
+	}
+	if !gen1a {
+		return MifareClassicMagicUnknown, nil
+	}
+	return MifareClassicMagicGen1a, nil
+}
+
+// ProbeGen2 reports whether the card accepts writing block 0 like a Gen2
+// card. It WRITES block 0 with its current content, which requires the key
+// of sector 0 in the key store. Cards allowing to write block 0 only once,
+// known as FUID or UFUID, lock it for good on this write.
+func (m *MifareClassic) ProbeGen2(uid []byte) (bool, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	if err := m.authenticateSector(uid, 0); err != nil {
+		return false, err
+	}
+	block, err := m.readDataBlock(0)
+	if err != nil {
+		return false, m.recover(err)
+	}
+	if err := m.writeDataBlock(0, block); err != nil {
+		// a genuine card refuses to write the manufacturer block
+		return false, m.recover(err)
+	}
+	return true, nil
+}
+
+// detectGen1a sends the backdoor commands and selects the card again
+// afterwards. The lock of the device must be held.
+func (m *MifareClassic) detectGen1a() (bool, error) {
+	err := m.unlockGen1a()
+	if err := m.rawMode(false); err != nil {
+		return false, err
+	}
+	var status StatusError
+	if err != nil && err != ErrNoMagicCard && !errors.As(err, &status) {
+		return false, err
+	}
+	if err := m.reselect(); err != nil {
+		return false, err
+	}
+	return err == nil, nil
+}
+
+// WriteManufacturerBlock writes block 0 of a Gen1a or Gen2 magic card, which
+// changes the UID of the card. The block needs the layout of the UID length
+// of the card, a 4 byte UID is refused if its BCC does not match. A card which is no Gen1a card is written like a Gen2 card, which
+// requires the key of sector 0 in the key store. The card is selected again
+// afterwards with its new UID, ErrNoMagicCard is returned if it refused the
+// write.
+func (m *MifareClassic) WriteManufacturerBlock(uid []byte, block []byte) error {
+	if _, err := ParseMifareClassicManufacturer(block, len(uid)); err != nil {
+		return err
+	}
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	gen1a, err := m.detectGen1a()
+	if err != nil {
+		return err
+	}
+	if gen1a {
+		if err = m.unlockGen1a(); err == nil {
+			err = m.writeGen1a(block)
+		}
+		if err := m.rawMode(false); err != nil {
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		return m.reselect()
+	}
+	if err := m.authenticateSector(uid, 0); err != nil {
+		return err
+	}
+	if err := m.writeDataBlock(0, block); err != nil {
+		var status StatusError
+		if !errors.As(err, &status) {
+			return err
+		}
+		// a genuine card refuses to write the manufacturer block
+		if err := m.reselect(); err != nil {
+			return err
+		}
+		return ErrNoMagicCard
+	}
+	return m.reselect()
+}
+
+// rawMode disables the CRC handling of the PN532 to send the backdoor
+// commands. The lock of the device must be held.
+func (m *MifareClassic) rawMode(raw bool) error {
+	var crc uint8 = 0x80
+	if raw {
+		crc = 0
+	}
+	if err := m.dev.updateRegister(REGISTER_CIU_TXMODE, 0x80, crc); err != nil {
+		return err
+	}
+	if err := m.dev.updateRegister(REGISTER_CIU_RXMODE, 0x80, crc); err != nil {
+		return err
+	}
+	return m.dev.writeRegister(REGISTER_CIU_BITFRAMING, 0x00)
+}
+
+// unlockGen1a halts the card and sends the backdoor commands. Only a Gen1a
+// card acknowledges them, leaving the card unlocked without authentication.
+// The lock of the device must be held.
+func (m *MifareClassic) unlockGen1a() error {
+	if err := m.rawMode(true); err != nil {
+		return err
+	}
+	// turn off Crypto1 of a previous authentication
+	if err := m.dev.updateRegister(REGISTER_CIU_STATUS2, 0x08, 0x00); err != nil {
+		return err
+	}
+	// HLTA, the card does not answer it
+	if _, err := m.dev.inCommunicateThru(withCRC(0x50, 0x00), 50*time.Millisecond); err != nil && err != StatusTimeout {
+		return err
+	}
+	if err := m.dev.writeRegister(REGISTER_CIU_BITFRAMING, 0x07); err != nil {
+		return err
+	}
+	if err := m.expectACK([]byte{mifareGen1aUnlock1}); err != nil {
+		return err
+	}
+	if err := m.dev.writeRegister(REGISTER_CIU_BITFRAMING, 0x00); err != nil {
+		return err
+	}
+	return m.expectACK([]byte{mifareGen1aUnlock2})
+}
+
+// writeGen1a writes block 0 of an unlocked Gen1a card. The lock of the
+// device must be held.
+func (m *MifareClassic) writeGen1a(block []byte) error {
+	if err := m.expectACK(withCRC(MIFARE_CMD_WRITE, 0x00)); err != nil {
+		return err
+	}
+	return m.expectACK(withCRC(block...))
+}
+
+// expectACK sends a raw frame and checks the card acknowledges it. The lock
+// of the device must be held.
+func (m *MifareClassic) expectACK(frame []byte) error {
+	response, err := m.dev.inCommunicateThru(frame, 100*time.Millisecond)
+	if err != nil {
+		return err
+	}
+	if len(response) != 1 || response[0]&0x0F != mifareACK {
+		return ErrNoMagicCard
+	}
+	return nil
+}
+
+// withCRC returns the frame followed by its CRC, for frames sent while the
+// CRC handling of the PN532 is disabled.
+func withCRC(frame ...byte) []byte {
+	crc := crcA(frame)
+	return append(append([]byte{}, frame...), crc[:]...)
+}
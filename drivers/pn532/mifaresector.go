@@ -0,0 +1,107 @@
+package pn532
+
+import "errors"
+
+// MifareClassicSector is the content of a sector.
+type MifareClassicSector struct {
+	Number uint8
+	// Blocks holds the data blocks, all blocks of the sector but the trailer
+	Blocks [][MifareClassicBlockSize]byte
+	// Trailer holds the parsed sector trailer. Key A and a hidden key B read
+	// as zeros, unless the key authenticating the sector is known.
+	Trailer MifareClassicTrailer
+	// Key is the key which authenticated the sector
+	Key MifareClassicKeyType
+}
+
+// ReadSector authenticates the sector once, with key A first and then key B
+// of the key store, and reads all its blocks.
+func (m *MifareClassic) ReadSector(uid []byte, sector uint8) (*MifareClassicSector, error) {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	if sector >= m.geometry.Sectors {
+		return nil, errors.New("the sector exceeds the card")
+	}
+	keyNumber, err := m.authenticateRead(uid, sector)
+	if err != nil {
+		return nil, err
+	}
+	content := &MifareClassicSector{Number: sector, Key: keyNumber}
+	trailer := m.geometry.TrailerBlock(sector)
+	for block := m.geometry.FirstBlock(sector); block < trailer; block++ {
+		data, err := m.readDataBlock(uint8(block))
+		if err != nil {
+			m.recover(err)
+			return nil, err
+		}
+		var blockData [MifareClassicBlockSize]byte
+		copy(blockData[:], data)
+		content.Blocks = append(content.Blocks, blockData)
+	}
+	data, err := m.readDataBlock(uint8(trailer))
+	if err != nil {
+		m.recover(err)
+		return nil, err
+	}
+	if content.Trailer, err = ParseMifareClassicTrailer(data); err != nil {
+		return nil, err
+	}
+	if keyNumber == MifareClassicKeyA {
+		content.Trailer.KeyA = append(MifareClassicKey{}, m.SectorKey(sector, keyNumber)...)
+	} else {
+		content.Trailer.KeyB = append(MifareClassicKey{}, m.SectorKey(sector, keyNumber)...)
+	}
+	return content, nil
+}
+
+// WriteSector authenticates the sector once and writes all its data blocks.
+// The data must cover all blocks of the sector except the trailer, for
+// sector 0 the manufacturer block is skipped as well. Use WriteSectorTrailer
+// to change the trailer.
+func (m *MifareClassic) WriteSector(uid []byte, sector uint8, data []byte) error {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	if sector >= m.geometry.Sectors {
+		return errors.New("the sector exceeds the card")
+	}
+	first := m.geometry.FirstBlock(sector)
+	if sector == 0 {
+		first = 1
+	}
+	trailer := m.geometry.TrailerBlock(sector)
+	if len(data) != int(trailer-first)*MifareClassicBlockSize {
+		return errors.New("the data does not match the data blocks of the sector")
+	}
+	if err := m.authenticateSector(uid, sector); err != nil {
+		m.recover(err)
+		return err
+	}
+	for block := first; block < trailer; block++ {
+		if err := m.writeDataBlock(uint8(block), data[:MifareClassicBlockSize]); err != nil {
+			m.recover(err)
+			return err
+		}
+		data = data[MifareClassicBlockSize:]
+	}
+	return nil
+}
+
+// authenticateRead authenticates the sector for reading, with key A first.
+// It returns the key which authenticated the sector. The lock of the device
+// must be held.
+func (m *MifareClassic) authenticateRead(uid []byte, sector uint8) (MifareClassicKeyType, error) {
+	block := m.geometry.FirstBlock(sector)
+	err := m.authenticate(uid, block, MifareClassicKeyA, m.SectorKey(sector, MifareClassicKeyA))
+	if err == nil {
+		return MifareClassicKeyA, nil
+	}
+	if err := m.recover(err); err != nil {
+		return MifareClassicKeyA, err
+	}
+	err = m.authenticate(uid, block, MifareClassicKeyB, m.SectorKey(sector, MifareClassicKeyB))
+	if err != nil {
+		m.recover(err)
+		return MifareClassicKeyB, err
+	}
+	return MifareClassicKeyB, nil
+}
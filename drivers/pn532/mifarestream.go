@@ -0,0 +1,153 @@
+package pn532
+
+import (
+	"errors"
+	"io"
+)
+
+// MifareClassicStream exposes the data blocks of a card as one byte stream,
+// skipping the manufacturer block and the sector trailers. It implements
+// io.ReaderAt and io.WriterAt. Each call authenticates the sectors it
+// accesses, with the keys of the key store of the card.
+type MifareClassicStream struct {
+	card *MifareClassic
+	uid  []byte
+	// the sector authenticated within the current call, -1 if none. Other
+	// commands between two calls may have reset the authentication.
+	sector int
+	// the sector is authenticated for writing
+	writable bool
+}
+
+// NewMifareClassicStream returns a stream over the card with the given UID.
+func NewMifareClassicStream(card *MifareClassic, uid []byte) *MifareClassicStream {
+	return &MifareClassicStream{card: card, uid: append([]byte{}, uid...), sector: -1}
+}
+
+// Size returns the number of bytes of the stream.
+func (s *MifareClassicStream) Size() int64 {
+	g := s.card.geometry
+	// all blocks except block 0 and the trailers
+	return int64(g.TotalBlocks()-1-uint32(g.Sectors)) * MifareClassicBlockSize
+}
+
+// block returns the card block holding the n-th data block of the stream.
+func (s *MifareClassicStream) block(n uint32) uint32 {
+	g := s.card.geometry
+	n++ // block 0
+	for sector := uint8(0); sector < g.Sectors; sector++ {
+		data := g.BlocksInSector(sector) - 1
+		if n < data {
+			return g.FirstBlock(sector) + n
+		}
+		n -= data
+	}
+	return g.TotalBlocks()
+}
+
+// ReadAt reads len(p) bytes starting at offset off.
+func (s *MifareClassicStream) ReadAt(p []byte, off int64) (int, error) {
+	s.card.dev.mu.Lock()
+	defer s.card.dev.mu.Unlock()
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	s.sector = -1
+	n := 0
+	for n < len(p) {
+		if off >= s.Size() {
+			return n, io.EOF
+		}
+		data, err := s.readBlock(s.block(uint32(off / MifareClassicBlockSize)))
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], data[off%MifareClassicBlockSize:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// WriteAt writes len(p) bytes starting at offset off. Blocks only partially
+// written are read first.
+func (s *MifareClassicStream) WriteAt(p []byte, off int64) (int, error) {
+	s.card.dev.mu.Lock()
+	defer s.card.dev.mu.Unlock()
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	s.sector = -1
+	n := 0
+	for n < len(p) {
+		if off >= s.Size() {
+			return n, io.ErrShortWrite
+		}
+		block := s.block(uint32(off / MifareClassicBlockSize))
+		start := int(off % MifareClassicBlockSize)
+		var data [MifareClassicBlockSize]byte
+		if start != 0 || len(p)-n < MifareClassicBlockSize {
+			current, err := s.readBlock(block)
+			if err != nil {
+				return n, err
+			}
+			copy(data[:], current)
+		}
+		copied := copy(data[start:], p[n:])
+		if err := s.writeBlock(block, data[:]); err != nil {
+			return n, err
+		}
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// readBlock authenticates the sector of the block if needed and reads it. The
+// lock of the device must be held.
+func (s *MifareClassicStream) readBlock(block uint32) ([]byte, error) {
+	sector := s.card.geometry.SectorOfBlock(block)
+	if s.sector != int(sector) {
+		if err := s.authenticate(sector); err != nil {
+			return nil, err
+		}
+	}
+	data, err := s.card.readDataBlock(uint8(block))
+	if err != nil {
+		s.sector = -1
+		s.card.recover(err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeBlock authenticates the sector of the block for writing if needed and
+// writes it. The lock of the device must be held.
+func (s *MifareClassicStream) writeBlock(block uint32, data []byte) error {
+	sector := s.card.geometry.SectorOfBlock(block)
+	if s.sector != int(sector) || !s.writable {
+		s.sector = -1
+		if err := s.card.authenticateSector(s.uid, sector); err != nil {
+			s.card.recover(err)
+			return err
+		}
+		s.sector, s.writable = int(sector), true
+	}
+	if err := s.card.writeDataBlock(uint8(block), data); err != nil {
+		s.sector = -1
+		s.card.recover(err)
+		return err
+	}
+	return nil
+}
+
+// authenticate authenticates the sector for reading. The lock of the device
+// must be held.
+func (s *MifareClassicStream) authenticate(sector uint8) error {
+	s.sector = -1
+	if _, err := s.card.authenticateRead(s.uid, sector); err != nil {
+		return err
+	}
+	s.sector, s.writable = int(sector), false
+	return nil
+}
@@ -0,0 +1,102 @@
+package pn532
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidValueBlock is returned for a block not holding the redundant
+// value block layout.
+var ErrInvalidValueBlock = errors.New("invalid MIFARE Classic value block")
+
+// EncodeMifareClassicValueBlock returns the value block layout: the value,
+// its inverse and the value again, followed by the address byte stored four
+// times, inverted in the odd bytes. The value is stored LSB first.
+func EncodeMifareClassicValueBlock(value int32, address uint8) [MifareClassicBlockSize]byte {
+	var block [MifareClassicBlockSize]byte
+	v := uint32(value)
+	for i := 0; i < 4; i++ {
+		b := byte(v >> (8 * i))
+		block[i] = b
+		block[4+i] = ^b
+		block[8+i] = b
+	}
+	block[12] = address
+	block[13] = ^address
+	block[14] = address
+	block[15] = ^address
+	return block
+}
+
+// DecodeMifareClassicValueBlock returns the value and the address byte of a
+// value block. It returns ErrInvalidValueBlock if the redundant copies do not
+// match.
+func DecodeMifareClassicValueBlock(block []byte) (int32, uint8, error) {
+	if len(block) != MifareClassicBlockSize {
+		return 0, 0, ErrInvalidValueBlock
+	}
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if block[i] != block[8+i] || block[i] != ^block[4+i] {
+			return 0, 0, ErrInvalidValueBlock
+		}
+		v |= uint32(block[i]) << (8 * i)
+	}
+	if block[12] != block[14] || block[13] != block[15] || block[12] != ^block[13] {
+		return 0, 0, ErrInvalidValueBlock
+	}
+	return int32(v), block[12], nil
+}
+
+// WriteValueBlock formats the block as value block holding the value. The
+// address byte is free to use, for backups it usually holds the block number.
+func (m *MifareClassic) WriteValueBlock(blockNumber uint8, value int32, address uint8) error {
+	block := EncodeMifareClassicValueBlock(value, address)
+	return m.WriteDataBlock(blockNumber, block[:])
+}
+
+// ReadValueBlock reads the block and returns its value and address byte. The
+// integrity of the value block is validated.
+func (m *MifareClassic) ReadValueBlock(blockNumber uint8) (int32, uint8, error) {
+	data, err := m.ReadDataBlock(blockNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	return DecodeMifareClassicValueBlock(data)
+}
+
+// Increment adds delta to the value block and transfers the result back to
+// the same block.
+func (m *MifareClassic) Increment(blockNumber uint8, delta uint32) error {
+	return m.valueOperation(MIFARE_CMD_INCREMENT, blockNumber, delta, blockNumber)
+}
+
+// Decrement subtracts delta from the value block and transfers the result
+// back to the same block.
+func (m *MifareClassic) Decrement(blockNumber uint8, delta uint32) error {
+	return m.valueOperation(MIFARE_CMD_DECREMENT, blockNumber, delta, blockNumber)
+}
+
+// Restore copies the value block src to the block dst of the same sector,
+// like used to recover a value from its backup block.
+func (m *MifareClassic) Restore(src uint8, dst uint8) error {
+	return m.valueOperation(MIFARE_CMD_STORE, src, 0, dst)
+}
+
+// valueOperation loads the value block into the transfer buffer of the card,
+// applies the operation and writes the transfer buffer to the destination.
+// The value block only changes once the transfer has succeeded.
+func (m *MifareClassic) valueOperation(command byte, src uint8, operand uint32, dst uint8) error {
+	m.dev.mu.Lock()
+	defer m.dev.mu.Unlock()
+	operation := [...]byte{
+		command, src,
+		byte(operand), byte(operand >> 8), byte(operand >> 16), byte(operand >> 24),
+	}
+	if _, err := m.dev.inDataExchange(operation[:], 100*time.Millisecond); err != nil {
+		return err
+	}
+	transfer := [...]byte{MIFARE_CMD_TRANSFER, dst}
+	_, err := m.dev.inDataExchange(transfer[:], 100*time.Millisecond)
+	return err
+}
@@ -0,0 +1,89 @@
+package pn532
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Value blocks in the layout of the NXP MF1S50YYX data sheet.
+var valueBlockVectors = []struct {
+	name    string
+	value   int32
+	address uint8
+	block   []byte
+}{
+	{
+		"zero",
+		0, 0x00,
+		[]byte{0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, 0x00, 0xFF},
+	},
+	{
+		"positive",
+		1234567, 0x05,
+		[]byte{0x87, 0xD6, 0x12, 0x00, 0x78, 0x29, 0xED, 0xFF, 0x87, 0xD6, 0x12, 0x00, 0x05, 0xFA, 0x05, 0xFA},
+	},
+	{
+		"negative",
+		-2, 0x3C,
+		[]byte{0xFE, 0xFF, 0xFF, 0xFF, 0x01, 0x00, 0x00, 0x00, 0xFE, 0xFF, 0xFF, 0xFF, 0x3C, 0xC3, 0x3C, 0xC3},
+	},
+	{
+		"maximum",
+		0x7FFFFFFF, 0xFF,
+		[]byte{0xFF, 0xFF, 0xFF, 0x7F, 0x00, 0x00, 0x00, 0x80, 0xFF, 0xFF, 0xFF, 0x7F, 0xFF, 0x00, 0xFF, 0x00},
+	},
+}
+
+func TestEncodeMifareClassicValueBlock(t *testing.T) {
+	for _, vector := range valueBlockVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			if got := EncodeMifareClassicValueBlock(vector.value, vector.address); !bytes.Equal(got[:], vector.block) {
+				t.Errorf("EncodeMifareClassicValueBlock = % X, want % X", got, vector.block)
+			}
+		})
+	}
+}
+
+func TestDecodeMifareClassicValueBlock(t *testing.T) {
+	for _, vector := range valueBlockVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			value, address, err := DecodeMifareClassicValueBlock(vector.block)
+			if err != nil {
+				t.Fatalf("DecodeMifareClassicValueBlock: %v", err)
+			}
+			if value != vector.value || address != vector.address {
+				t.Errorf("DecodeMifareClassicValueBlock = %d, %02X, want %d, %02X", value, address, vector.value, vector.address)
+			}
+		})
+	}
+}
+
+func TestDecodeMifareClassicValueBlockErrors(t *testing.T) {
+	valid := valueBlockVectors[1].block
+	corrupt := func(i int) []byte {
+		block := append([]byte{}, valid...)
+		block[i] ^= 0x01
+		return block
+	}
+	tests := []struct {
+		name  string
+		block []byte
+	}{
+		{"value", corrupt(0)},
+		{"inverted value", corrupt(5)},
+		{"value copy", corrupt(11)},
+		{"address", corrupt(12)},
+		{"inverted address", corrupt(13)},
+		{"address copy", corrupt(14)},
+		{"inverted address copy", corrupt(15)},
+		{"short block", valid[:15]},
+		{"zero block", make([]byte, MifareClassicBlockSize)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := DecodeMifareClassicValueBlock(test.block); err != ErrInvalidValueBlock {
+				t.Errorf("DecodeMifareClassicValueBlock: %v, want ErrInvalidValueBlock", err)
+			}
+		})
+	}
+}
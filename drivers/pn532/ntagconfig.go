@@ -0,0 +1,313 @@
+package pn532
+
+import (
+	"errors"
+	"time"
+)
+
+// The configuration pages at the end of the memory of NTAG21x and MIFARE
+// Ultralight EV1 tags. See NXP NTAG213/215/216 data sheet 8.5.7.
+const (
+	ntagConfigPages = 4 // CFG0, CFG1, PWD and PACK
+	ntagProt        = 0x80
+	ntagCfgLck      = 0x40
+	ntagNFCCntEn    = 0x10
+	ntagNFCCntPwd   = 0x08
+	ntagAuthLim     = 0x07
+	ntagStrgModEn   = 0x04
+)
+
+// NTAGMirror selects what the tag mirrors as ASCII into its user memory
+type NTAGMirror uint8
+
+const (
+	NTAGMirrorNone          NTAGMirror = 0 // Mirror disabled
+	NTAGMirrorUID           NTAGMirror = 1 // The UID, 14 characters
+	NTAGMirrorCounter       NTAGMirror = 2 // The NFC counter, 6 characters
+	NTAGMirrorUIDAndCounter NTAGMirror = 3 // The UID, a 'x' and the NFC counter, 21 characters
+)
+
+// Length returns the number of ASCII characters the tag mirrors.
+func (m NTAGMirror) Length() int {
+	switch m {
+	case NTAGMirrorUID:
+		return 14
+	case NTAGMirrorCounter:
+		return 6
+	case NTAGMirrorUIDAndCounter:
+		return 21
+	}
+	return 0
+}
+
+// NTAGAuth0Disabled disables the password protection when used as AUTH0, as
+// it is above the last page of every tag.
+const NTAGAuth0Disabled = 0xFF
+
+var (
+	ErrPasswordAuth = errors.New("the password was not accepted")
+	ErrPACKMismatch = errors.New("the password acknowledge does not match")
+	ErrUnknownTag   = errors.New("the tag type is unknown")
+	ErrNotNTAG      = errors.New("the tag is no NTAG21x")
+)
+
+// NTAGConfig is the content of the configuration pages.
+type NTAGConfig struct {
+	// Mirror selects what is mirrored, starting at byte MirrorByte of page
+	// MirrorPage. See SetMirrorOffset.
+	Mirror     NTAGMirror
+	MirrorByte uint8
+	MirrorPage uint8
+	// StrgModEn enables the strong modulation
+	StrgModEn bool
+	// AUTH0 is the first page protected by the password, NTAGAuth0Disabled
+	// disables the protection
+	AUTH0 uint8
+	// Prot protects reads as well, otherwise only writes are protected
+	Prot bool
+	// CfgLck locks the configuration pages for good, except PWD and PACK
+	CfgLck bool
+	// NFCCounter enables the NFC counter, NFCCounterPwd protects reading it
+	NFCCounter    bool
+	NFCCounterPwd bool
+	// AuthLim limits the failed password attempts to 2^AuthLim, 0 allows
+	// unlimited attempts
+	AuthLim uint8
+	// PWD is the password, it always reads as zeros
+	PWD [4]byte
+	// PACK is the password acknowledge returned on a successful PWD_AUTH
+	PACK [2]byte
+}
+
+// ParseNTAGConfig decodes the 16 bytes of the configuration pages.
+func ParseNTAGConfig(pages []byte) (NTAGConfig, error) {
+	config := NTAGConfig{}
+	if len(pages) != ntagConfigPages*UltralightPageSize {
+		return config, errors.New("the configuration needs 16 bytes")
+	}
+	config.Mirror = NTAGMirror(pages[0] >> 6)
+	config.MirrorByte = pages[0] >> 4 & 0x03
+	config.StrgModEn = pages[0]&ntagStrgModEn != 0
+	config.MirrorPage = pages[2]
+	config.AUTH0 = pages[3]
+	access := pages[4]
+	config.Prot = access&ntagProt != 0
+	config.CfgLck = access&ntagCfgLck != 0
+	config.NFCCounter = access&ntagNFCCntEn != 0
+	config.NFCCounterPwd = access&ntagNFCCntPwd != 0
+	config.AuthLim = access & ntagAuthLim
+	copy(config.PWD[:], pages[8:12])
+	copy(config.PACK[:], pages[12:14])
+	return config, nil
+}
+
+// Encode returns the 16 bytes of the configuration pages.
+func (c *NTAGConfig) Encode() [ntagConfigPages * UltralightPageSize]byte {
+	var pages [ntagConfigPages * UltralightPageSize]byte
+	pages[0] = byte(c.Mirror&0x03)<<6 | (c.MirrorByte&0x03)<<4
+	if c.StrgModEn {
+		pages[0] |= ntagStrgModEn
+	}
+	pages[2] = c.MirrorPage
+	pages[3] = c.AUTH0
+	access := c.AuthLim & ntagAuthLim
+	if c.Prot {
+		access |= ntagProt
+	}
+	if c.CfgLck {
+		access |= ntagCfgLck
+	}
+	if c.NFCCounter {
+		access |= ntagNFCCntEn
+	}
+	if c.NFCCounterPwd {
+		access |= ntagNFCCntPwd
+	}
+	pages[4] = access
+	copy(pages[8:12], c.PWD[:])
+	copy(pages[12:14], c.PACK[:])
+	return pages
+}
+
+// SetMirrorOffset places the mirror at the byte offset within the user
+// memory of the given size, which starts at page 4 after the capability
+// container. Use it to mirror into a placeholder of a NDEF record. The mirror
+// must fit into the user memory, it would overwrite the lock or configuration
+// pages otherwise.
+func (c *NTAGConfig) SetMirrorOffset(mirror NTAGMirror, offset int, userMemory int) error {
+	if offset < 0 || offset+mirror.Length() > userMemory {
+		return errors.New("the mirror exceeds the user memory")
+	}
+	c.Mirror = mirror
+	c.MirrorPage = uint8(4 + offset/UltralightPageSize)
+	c.MirrorByte = uint8(offset % UltralightPageSize)
+	if mirror&NTAGMirrorCounter != 0 {
+		c.NFCCounter = true
+	}
+	return nil
+}
+
+// configPage returns the first configuration page, the tag is identified
+// once by GET_VERSION. The lock of the device must be held.
+func (u *Ultralight) configPage() (uint8, error) {
+	if u.noVersion {
+		return 0, ErrNoVersion
+	}
+	if u.pages == 0 {
+		version, err := u.getVersion()
+		if err == ErrNoVersion {
+			u.noVersion = true
+		}
+		if err != nil {
+			return 0, err
+		}
+		if version.Pages() == 0 {
+			return 0, ErrUnknownTag
+		}
+		u.pages = version.Pages()
+		u.userMemory = version.UserMemory()
+		u.pagesPerLockBit = version.PagesPerLockBit()
+		u.ntag = version.IsNTAG()
+	}
+	return u.pages - ntagConfigPages, nil
+}
+
+// ReadConfig reads the configuration pages. With Prot set they are only
+// readable after PasswordAuth.
+func (u *Ultralight) ReadConfig() (NTAGConfig, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.readConfig()
+}
+
+func (u *Ultralight) readConfig() (NTAGConfig, error) {
+	page, err := u.configPage()
+	if err != nil {
+		return NTAGConfig{}, err
+	}
+	pages, err := u.readPages(page)
+	if err != nil {
+		return NTAGConfig{}, err
+	}
+	return ParseNTAGConfig(pages)
+}
+
+// WriteConfig writes the configuration pages. PWD and PACK are written
+// before AUTH0, so the protection only becomes active with the new password
+// in place. Setting CfgLck locks the configuration for good. Once protected
+// the configuration is only writable after PasswordAuth.
+func (u *Ultralight) WriteConfig(config *NTAGConfig) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.writeConfig(config)
+}
+
+func (u *Ultralight) writeConfig(config *NTAGConfig) error {
+	page, err := u.configPage()
+	if err != nil {
+		return err
+	}
+	pages := config.Encode()
+	for _, i := range []uint8{2, 3, 1, 0} {
+		if err := u.writePage(page+i, pages[i*UltralightPageSize:(i+1)*UltralightPageSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PasswordAuth authenticates with the password and returns the PACK of the
+// tag. Failed attempts count against AuthLim.
+func (u *Ultralight) PasswordAuth(pwd [4]byte) ([2]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.passwordAuth(pwd)
+}
+
+func (u *Ultralight) passwordAuth(pwd [4]byte) ([2]byte, error) {
+	var pack [2]byte
+	command := [...]byte{NTAG_CMD_PWD_AUTH, pwd[0], pwd[1], pwd[2], pwd[3]}
+	response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+	if err != nil {
+		var status StatusError
+		if errors.As(err, &status) {
+			// the tag answers a wrong password with a NAK and goes back to
+			// idle, select it again for the next command
+			if err := u.reselect(); err != nil {
+				return pack, err
+			}
+			return pack, ErrPasswordAuth
+		}
+		return pack, err
+	}
+	if len(response) != len(pack) {
+		return pack, ErrPasswordAuth
+	}
+	copy(pack[:], response)
+	return pack, nil
+}
+
+// VerifyPassword authenticates with the password and checks the PACK, which
+// tells a genuine tag from one accepting any password.
+func (u *Ultralight) VerifyPassword(pwd [4]byte, pack [2]byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	answer, err := u.passwordAuth(pwd)
+	if err != nil {
+		return err
+	}
+	if answer != pack {
+		return ErrPACKMismatch
+	}
+	return nil
+}
+
+// Protect sets the password and protects the pages starting at auth0 from
+// writing, and from reading as well if readProtect is set. A tag already
+// protected needs PasswordAuth first.
+func (u *Ultralight) Protect(pwd [4]byte, pack [2]byte, auth0 uint8, readProtect bool) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	config, err := u.readConfig()
+	if err != nil {
+		return err
+	}
+	config.PWD = pwd
+	config.PACK = pack
+	config.AUTH0 = auth0
+	config.Prot = readProtect
+	return u.writeConfig(&config)
+}
+
+// Unprotect authenticates with the password and disables the protection. The
+// password itself stays stored on the tag.
+func (u *Ultralight) Unprotect(pwd [4]byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	if _, err := u.passwordAuth(pwd); err != nil {
+		return err
+	}
+	config, err := u.readConfig()
+	if err != nil {
+		return err
+	}
+	config.AUTH0 = NTAGAuth0Disabled
+	config.Prot = false
+	return u.writeConfigPages(&config)
+}
+
+// writeConfigPages writes CFG1 and CFG0 only, keeping PWD and PACK which read
+// as zeros. The lock of the device must be held.
+func (u *Ultralight) writeConfigPages(config *NTAGConfig) error {
+	page, err := u.configPage()
+	if err != nil {
+		return err
+	}
+	pages := config.Encode()
+	for _, i := range []uint8{1, 0} {
+		if err := u.writePage(page+i, pages[i*UltralightPageSize:(i+1)*UltralightPageSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package pn532
+
+import (
+	"errors"
+	"time"
+)
+
+// The pages read by a single FAST_READ. The answer has to fit into a normal
+// information frame of the PN532.
+const ntagFastReadPages = 60
+
+// FastRead reads the pages first to last, both included. Larger ranges are
+// split into several FAST_READ commands, each fitting into a PN532 frame.
+func (u *Ultralight) FastRead(first uint8, last uint8) ([]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.fastRead(first, last)
+}
+
+func (u *Ultralight) fastRead(first uint8, last uint8) ([]byte, error) {
+	if last < first {
+		return nil, errors.New("invalid page range")
+	}
+	data := make([]byte, 0, (int(last)-int(first)+1)*UltralightPageSize)
+	for start := int(first); start <= int(last); start += ntagFastReadPages {
+		end := start + ntagFastReadPages - 1
+		if end > int(last) {
+			end = int(last)
+		}
+		command := [...]byte{NTAG_CMD_FAST_READ, uint8(start), uint8(end)}
+		response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+		if err != nil {
+			return nil, err
+		}
+		if len(response) != (end-start+1)*UltralightPageSize {
+			return nil, errors.New("invalid FAST_READ response")
+		}
+		data = append(data, response...)
+	}
+	return data, nil
+}
+
+// Dump reads all pages of the tag, which is identified by GET_VERSION. Pages
+// protected by the password read as zeros or fail without PasswordAuth.
+func (u *Ultralight) Dump() ([]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	if _, err := u.configPage(); err != nil {
+		return nil, err
+	}
+	return u.fastRead(0, u.pages-1)
+}
+
+// ReadCounter reads the NFC counter, which counts the first read after each
+// activation of the tag. The counter must be enabled, see NTAGConfig.
+func (u *Ultralight) ReadCounter() (uint32, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	command := [...]byte{NTAG_CMD_READ_CNT, 0x02}
+	response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+	if err != nil {
+		return 0, err
+	}
+	if len(response) != 3 {
+		return 0, errors.New("invalid READ_CNT response")
+	}
+	// LSB first
+	return uint32(response[0]) | uint32(response[1])<<8 | uint32(response[2])<<16, nil
+}
+
+// SetMirror mirrors the UID and/or the NFC counter as ASCII into the user
+// memory at the byte offset, see NTAGConfig.SetMirrorOffset. Mirroring the
+// counter enables it. Only NTAG21x tags mirror, other tags return
+// ErrNotNTAG.
+func (u *Ultralight) SetMirror(mirror NTAGMirror, offset int) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	config, err := u.readConfig()
+	if err != nil {
+		return err
+	}
+	// the MIFARE Ultralight EV1 has MOD and RFUI bytes in place of the mirror
+	if !u.ntag {
+		return ErrNotNTAG
+	}
+	if err := config.SetMirrorOffset(mirror, offset, u.userMemory); err != nil {
+		return err
+	}
+	return u.writeConfigPages(&config)
+}
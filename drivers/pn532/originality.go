@@ -0,0 +1,157 @@
+package pn532
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidSignature is returned if the originality signature does not
+// match the UID, the tag is not a genuine NXP product.
+var ErrInvalidSignature = errors.New("invalid originality signature")
+
+// NTAGOriginalityKey is the public key NXP signs the UIDs of NTAG21x tags
+// with. See NXP AN11350 NTAG21x Originality Signature Validation.
+var NTAGOriginalityKey = []byte{
+	0x04, 0x49, 0x4E, 0x1A, 0x38, 0x6D, 0x3D, 0x3C, 0xFE, 0x3D, 0xC1, 0x0E, 0x5D, 0xE6, 0x8A, 0x49,
+	0x9B, 0x1C, 0x20, 0x2D, 0xB5, 0xB1, 0x32, 0x39, 0x3E, 0x89, 0xED, 0x19, 0xFE, 0x5B, 0xE8, 0xBC,
+	0x61,
+}
+
+// UltralightEV1OriginalityKey is the public key NXP signs the UIDs of MIFARE
+// Ultralight EV1 tags with.
+var UltralightEV1OriginalityKey = []byte{
+	0x04, 0x90, 0x93, 0x3B, 0xDC, 0xD6, 0xE9, 0x9B, 0x4E, 0x25, 0x5E, 0x3D, 0xA5, 0x53, 0x89, 0xA8,
+	0x27, 0x56, 0x4E, 0x11, 0x71, 0x8E, 0x01, 0x72, 0x92, 0xFA, 0xF2, 0x32, 0x26, 0xA9, 0x66, 0x14,
+	0xB8,
+}
+
+// secp128r1 as defined in SEC 2, the curve of the originality signature.
+// Its coefficient a is p - 3.
+var secp128r1 = struct {
+	p, n, b, gx, gy *big.Int
+}{
+	p:  hexInt("FFFFFFFDFFFFFFFFFFFFFFFFFFFFFFFF"),
+	n:  hexInt("FFFFFFFE0000000075A30D1B9038A115"),
+	b:  hexInt("E87579C11079F43DD824993C2CEE5ED3"),
+	gx: hexInt("161FF7528B899B2D0C28607CA52C5B86"),
+	gy: hexInt("CF5AC8395BAFEB13C02DA292DDED7A83"),
+}
+
+func hexInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 16)
+	return i
+}
+
+// point is an affine point of the curve, nil coordinates are the point at
+// infinity.
+type point struct {
+	x, y *big.Int
+}
+
+func (q point) infinity() bool {
+	return q.x == nil
+}
+
+// onCurve reports whether y² = x³ - 3x + b holds.
+func (q point) onCurve() bool {
+	c := &secp128r1
+	if q.x.Cmp(c.p) >= 0 || q.y.Cmp(c.p) >= 0 {
+		return false
+	}
+	left := new(big.Int).Mul(q.y, q.y)
+	left.Mod(left, c.p)
+	right := new(big.Int).Mul(q.x, q.x)
+	right.Sub(right, big.NewInt(3))
+	right.Mul(right, q.x)
+	right.Add(right, c.b)
+	right.Mod(right, c.p)
+	return left.Cmp(right) == 0
+}
+
+// add returns q + r.
+func (q point) add(r point) point {
+	c := &secp128r1
+	if q.infinity() {
+		return r
+	}
+	if r.infinity() {
+		return q
+	}
+	var slope *big.Int
+	if q.x.Cmp(r.x) == 0 {
+		sum := new(big.Int).Add(q.y, r.y)
+		if sum.Mod(sum, c.p).Sign() == 0 {
+			return point{}
+		}
+		// tangent: (3x² - 3) / 2y
+		numerator := new(big.Int).Mul(q.x, q.x)
+		numerator.Sub(numerator, big.NewInt(1))
+		numerator.Mul(numerator, big.NewInt(3))
+		denominator := new(big.Int).Lsh(q.y, 1)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, c.p))
+	} else {
+		numerator := new(big.Int).Sub(r.y, q.y)
+		denominator := new(big.Int).Sub(r.x, q.x)
+		denominator.Mod(denominator, c.p)
+		slope = numerator.Mul(numerator, denominator.ModInverse(denominator, c.p))
+	}
+	slope.Mod(slope, c.p)
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, q.x)
+	x.Sub(x, r.x)
+	x.Mod(x, c.p)
+	y := new(big.Int).Sub(q.x, x)
+	y.Mul(y, slope)
+	y.Sub(y, q.y)
+	y.Mod(y, c.p)
+	return point{x, y}
+}
+
+// multiply returns k·q by double and add.
+func (q point) multiply(k *big.Int) point {
+	result := point{}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.add(result)
+		if k.Bit(i) == 1 {
+			result = result.add(q)
+		}
+	}
+	return result
+}
+
+// VerifyOriginalitySignature checks the ECDSA signature r || s of the UID
+// against the uncompressed public key, UltralightVersion.OriginalityKey
+// selects the key of the tag. The UID is signed as is, without hashing.
+func VerifyOriginalitySignature(publicKey []byte, uid []byte, signature []byte) error {
+	c := &secp128r1
+	if len(publicKey) != 33 || publicKey[0] != 0x04 {
+		return errors.New("the public key needs 33 bytes in uncompressed form")
+	}
+	if len(signature) != 32 {
+		return errors.New("the signature needs 32 bytes")
+	}
+	key := point{new(big.Int).SetBytes(publicKey[1:17]), new(big.Int).SetBytes(publicKey[17:33])}
+	if !key.onCurve() {
+		return errors.New("the public key is not on the curve")
+	}
+	r := new(big.Int).SetBytes(signature[:16])
+	s := new(big.Int).SetBytes(signature[16:])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(c.n) >= 0 || s.Cmp(c.n) >= 0 {
+		return ErrInvalidSignature
+	}
+	e := new(big.Int).SetBytes(uid)
+	w := new(big.Int).ModInverse(s, c.n)
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, c.n)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, c.n)
+	generator := point{c.gx, c.gy}
+	sum := generator.multiply(u1).add(key.multiply(u2))
+	if sum.infinity() {
+		return ErrInvalidSignature
+	}
+	if new(big.Int).Mod(sum.x, c.n).Cmp(r) != 0 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
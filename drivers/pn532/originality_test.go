@@ -0,0 +1,113 @@
+package pn532
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestSecp128r1(t *testing.T) {
+	c := &secp128r1
+	generator := point{c.gx, c.gy}
+	if !generator.onCurve() {
+		t.Fatal("the generator is not on the curve")
+	}
+	if !generator.multiply(c.n).infinity() {
+		t.Error("n·G is not the point at infinity")
+	}
+	keys := []struct {
+		name string
+		key  []byte
+	}{
+		{"NTAGOriginalityKey", NTAGOriginalityKey},
+		{"UltralightEV1OriginalityKey", UltralightEV1OriginalityKey},
+	}
+	for _, key := range keys {
+		q := point{new(big.Int).SetBytes(key.key[1:17]), new(big.Int).SetBytes(key.key[17:33])}
+		if !q.onCurve() {
+			t.Errorf("%s is not on the curve", key.name)
+		}
+	}
+}
+
+func TestOriginalityKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		version UltralightVersion
+		want    []byte
+	}{
+		{"NTAG215", UltralightVersion{0x04, 0x04, 0x02, 0x01, 0x00, 0x11, 0x03}, NTAGOriginalityKey},
+		{"Ultralight EV1 MF0UL11", UltralightVersion{0x04, 0x03, 0x01, 0x01, 0x00, 0x0B, 0x03}, UltralightEV1OriginalityKey},
+		{"other vendor", UltralightVersion{0x05, 0x04, 0x02, 0x01, 0x00, 0x11, 0x03}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.version.OriginalityKey(); !bytes.Equal(got, test.want) {
+				t.Errorf("OriginalityKey = %X, want %X", got, test.want)
+			}
+		})
+	}
+}
+
+// signOriginality signs the UID like NXP does, ECDSA without hashing.
+func signOriginality(d, k *big.Int, uid []byte) []byte {
+	c := &secp128r1
+	r := point{c.gx, c.gy}.multiply(k).x
+	r.Mod(r, c.n)
+	s := new(big.Int).Mul(r, d)
+	s.Add(s, new(big.Int).SetBytes(uid))
+	s.Mul(s, new(big.Int).ModInverse(k, c.n))
+	s.Mod(s, c.n)
+	signature := make([]byte, 32)
+	r.FillBytes(signature[:16])
+	s.FillBytes(signature[16:])
+	return signature
+}
+
+func TestVerifyOriginalitySignature(t *testing.T) {
+	c := &secp128r1
+	d := hexInt("0123456789ABCDEF0123456789ABCDEF")
+	public := point{c.gx, c.gy}.multiply(d)
+	publicKey := make([]byte, 33)
+	publicKey[0] = 0x04
+	public.x.FillBytes(publicKey[1:17])
+	public.y.FillBytes(publicKey[17:33])
+
+	uid := []byte{0x04, 0xE1, 0x41, 0x2A, 0x6B, 0x52, 0x80}
+	signature := signOriginality(d, hexInt("00FEDCBA9876543210FEDCBA98765432"), uid)
+	if err := VerifyOriginalitySignature(publicKey, uid, signature); err != nil {
+		t.Fatalf("valid signature: %v", err)
+	}
+
+	otherUID := append([]byte{}, uid...)
+	otherUID[6] ^= 0x01
+	tampered := append([]byte{}, signature...)
+	tampered[31] ^= 0x01
+	tests := []struct {
+		name      string
+		publicKey []byte
+		uid       []byte
+		signature []byte
+		want      error
+	}{
+		{"other UID", publicKey, otherUID, signature, ErrInvalidSignature},
+		{"tampered signature", publicKey, uid, tampered, ErrInvalidSignature},
+		{"other key", NTAGOriginalityKey, uid, signature, ErrInvalidSignature},
+		{"zero signature", publicKey, uid, make([]byte, 32), ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := VerifyOriginalitySignature(test.publicKey, test.uid, test.signature); err != test.want {
+				t.Errorf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+	if err := VerifyOriginalitySignature(publicKey, uid, signature[:31]); err == nil {
+		t.Error("short signature accepted")
+	}
+	offCurve := append([]byte{}, publicKey...)
+	offCurve[32] ^= 0x01
+	if err := VerifyOriginalitySignature(offCurve, uid, signature); err == nil {
+		t.Error("public key off the curve accepted")
+	}
+}
@@ -10,10 +10,12 @@ package pn532
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"errors"
 	"machine"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -37,6 +39,7 @@ const Address = 0x24
 
 const (
 	BUFFSIZE                 = 64
+	MAXFRAMESIZE             = 262 // Maximum size of a normal information frame
 	COMMAND_SAMCONFIGURATION = 0x14
 )
 
@@ -50,31 +53,53 @@ const (
 const (
 	PN532_HOSTTOPN532 = 0xD4
 	PN532_PN532TOHOST = 0xD5
+	PN532_ERRORFRAME  = 0x7F // Application level error frame
 )
 
 // PN532 Commands
 const (
-	COMMAND_GETFIRMWAREVERSION  = 0x02
-	COMMAND_INLISTPASSIVETARGET = 0x4A // List passive targets
-	COMMAND_INDATAEXCHANGE      = 0x40 // Data exchange
+	COMMAND_GETFIRMWAREVERSION    = 0x02
+	COMMAND_READREGISTER          = 0x06 // Read registers of the PN532
+	COMMAND_WRITEREGISTER         = 0x08 // Write registers of the PN532
+	COMMAND_INCOMMUNICATETHRU     = 0x42 // Send raw frames to a target
+	COMMAND_INLISTPASSIVETARGET   = 0x4A // List passive targets
+	COMMAND_INDATAEXCHANGE        = 0x40 // Data exchange
+	COMMAND_TGINITASTARGET        = 0x8C // Configure the PN532 as target
+	COMMAND_TGGETDATA             = 0x86 // Get data from the initiator (DEP or ISO/IEC 14443-4)
+	COMMAND_TGSETDATA             = 0x8E // Send data to the initiator (DEP or ISO/IEC 14443-4)
+	COMMAND_TGGETINITIATORCOMMAND = 0x88 // Get a raw command from the initiator
+	COMMAND_TGRESPONSETOINITIATOR = 0x90 // Send a raw response to the initiator
+	COMMAND_TGSETMETADATA         = 0x94 // Send chained data to the initiator (DEP)
+	COMMAND_INJUMPFORDEP          = 0x56 // Activate a target in DEP mode
+	COMMAND_INATR                 = 0x50 // Send an ATR_REQ to a listed target
+	COMMAND_INPSL                 = 0x4E // Change the baud rate of a DEP target
 )
 
 const (
 	PN532_I2C_READY = 0x01
 )
 
+// ErrTimeout is returned when the PN532 has not answered within the timeout.
+var ErrTimeout = errors.New("pn532: timeout")
+
 const (
 	MIFARE_ISO14443A = 0x00
 )
 
-// Device wraps an I2C connection to a PN532 device.
+// Device wraps an I2C connection to a PN532 device. Every method runs its
+// commands including their responses under a lock, so concurrent calls do not
+// mix up frames on the bus. Sequences of commands like authenticating and
+// reading a block run within Do, which holds the lock for the whole sequence.
 type Device struct {
+	mu              *sync.Mutex // Guards the buffers and the command in flight, shared by copies
+	tx              *Device     // The view handed to Do, allocated on first use
 	bus             *machine.I2C
 	address         uint16
 	debug           bool
 	buffer          [BUFFSIZE]byte
 	txBuffer        [BUFFSIZE]byte
-	rxBuffer        [BUFFSIZE]byte
+	rxBuffer        [MAXFRAMESIZE + 1]byte
+	response        [MAXFRAMESIZE]byte // Responses carrying a variable amount of data
 	rdy             [1]byte
 	ackbuff         [6]byte
 	pn532ack        [6]byte // The ACK message from PN532
@@ -87,6 +112,7 @@ type Device struct {
 // This function only creates the Device object, it does not touch the device.
 func NewI2C(bus *machine.I2C) Device {
 	return Device{
+		mu:      &sync.Mutex{},
 		bus:     bus,
 		address: Address,
 		debug:   false,
@@ -101,12 +127,38 @@ func NewI2C(bus *machine.I2C) Device {
 	}
 }
 
+// Do runs a sequence of commands without commands of other goroutines in
+// between, for example a presence check deselecting the card between
+// AuthenticateBlock and ReadDataBlock. The commands of the sequence are sent
+// through tx, which is only valid within fn: create the MifareClassic,
+// Ultralight and other card types with tx. Calling methods of d itself
+// within fn deadlocks.
+func (d *Device) Do(fn func(tx *Device) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tx == nil {
+		d.tx = &Device{
+			mu:              &sync.Mutex{},
+			bus:             d.bus,
+			address:         d.address,
+			pn532ack:        d.pn532ack,
+			firmwareVersion: d.firmwareVersion,
+		}
+	}
+	d.tx.debug = d.debug
+	return fn(d.tx)
+}
+
 // enable/disable debugging
 func (d *Device) Debug(b bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.debug = b
 }
 
 func (d *Device) Configure() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	time.Sleep(10 * time.Millisecond)
 	return d.wakeup()
 }
@@ -122,7 +174,7 @@ func (d *Device) samconfig() error {
 	buffer[2] = 0x14 // timeout 50ms * 20 = 1 second
 	buffer[3] = 0x01 // use IRQ PIN!
 
-	if err := d.sendCommandCheckAck(buffer, 100*time.Millisecond); err != nil {
+	if err := d.sendCommandCheckAck(context.Background(), buffer, 100*time.Millisecond); err != nil {
 		return err
 	}
 	buffer = d.buffer[:9]
@@ -136,25 +188,44 @@ func (d *Device) samconfig() error {
 	return nil
 }
 
-func (d *Device) sendCommandCheckAck(command []byte, timeout time.Duration) error {
+// sendCommandCheckAck writes the command and waits until the response is
+// ready. If the context is done or the timeout expires first the command is
+// aborted, which leaves the PN532 ready for the next command.
+func (d *Device) sendCommandCheckAck(ctx context.Context, command []byte, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	// write the command
 	if err := d.writecommand(command); err != nil {
 		return err
 	}
-	if !d.waitready(timeout) {
-		return errors.New("waitready failed")
+	if err := d.waitready(ctx, timeout); err != nil {
+		d.abort()
+		return errors.Join(errors.New("waitready failed"), err)
 	}
 	d.i2cTuning()
 	if !d.isACK() {
 		return errors.New("readack failed")
 	}
 	d.i2cTuning()
-	if !d.waitready(timeout) {
-		return errors.New("second waitready failed")
+	if err := d.waitready(ctx, timeout); err != nil {
+		d.abort()
+		return errors.Join(errors.New("second waitready failed"), err)
 	}
 	return nil
 }
 
+// abort sends an ACK frame to the PN532, which aborts the command currently
+// processed. See [2] 6.2.1.3 ACK frame.
+func (d *Device) abort() error {
+	d.i2cTuning()
+	if err := d.bus.Tx(d.address, d.pn532ack[:], nil); err != nil {
+		return err
+	}
+	d.i2cTuning()
+	return nil
+}
+
 func (d *Device) writecommand(cmd []byte) error {
 	packet := d.txBuffer[:8+len(cmd)]
 	LEN := byte(len(cmd) + 1)
@@ -177,19 +248,28 @@ func (d *Device) writecommand(cmd []byte) error {
 	return nil
 }
 
-func (d *Device) waitready(timeout time.Duration) bool {
+// waitready polls the ready status until the PN532 is ready, the timeout has
+// expired or the context is done. A timeout of 0 only relies on the context.
+func (d *Device) waitready(ctx context.Context, timeout time.Duration) error {
 	const delay = 10 * time.Millisecond
 	timer := 1 * time.Millisecond
+	deadline, hasDeadline := ctx.Deadline()
 	for !d.isReady() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if hasDeadline && time.Now().After(deadline) {
+			return context.DeadlineExceeded
+		}
 		if timeout != 0 {
 			timer += 10 * time.Millisecond
 			if timer > timeout {
-				return false
+				return ErrTimeout
 			}
 		}
 		time.Sleep(delay)
 	}
-	return true
+	return nil
 }
 
 func (d *Device) isACK() bool {
@@ -209,6 +289,72 @@ func (d *Device) printBuffer(name string, buffer []byte) {
 	}
 }
 
+// exchange sends the command and reads its response frame into buffer. It
+// returns the data following the response code.
+func (d *Device) exchange(ctx context.Context, command []byte, buffer []byte, timeout time.Duration) ([]byte, error) {
+	if err := d.sendCommandCheckAck(ctx, command, timeout); err != nil {
+		return nil, err
+	}
+	return d.readresponse(command[0], buffer)
+}
+
+// inDataExchange sends data to the first listed target and returns its
+// answer, which is only valid until the next command.
+func (d *Device) inDataExchange(data []byte, timeout time.Duration) ([]byte, error) {
+	if len(data) > BUFFSIZE-10 {
+		return nil, errors.New("the given data exceeds the buffer")
+	}
+	buffer := d.buffer[:2+len(data)]
+	buffer[0] = COMMAND_INDATAEXCHANGE
+	buffer[1] = 1 // card number
+	copy(buffer[2:], data)
+	response, err := d.exchange(context.Background(), buffer, d.response[:], timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 {
+		return nil, errors.New("invalid InDataExchange response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return nil, err
+	}
+	return response[1:], nil
+}
+
+// readresponse reads the response frame of the given command into buffer and
+// validates the frame header and checksums. See [2] 6.2.1.1 for the frame
+// layout.
+func (d *Device) readresponse(command byte, buffer []byte) ([]byte, error) {
+	if err := d.readdata(buffer); err != nil {
+		return nil, err
+	}
+	d.printBuffer("Response", buffer)
+	if buffer[0] != PN532_PREAMBLE || buffer[1] != PN532_STARTCODE1 || buffer[2] != PN532_STARTCODE2 {
+		return nil, errors.New("invalid response frame")
+	}
+	length := int(buffer[3])
+	if byte(length)+buffer[4] != 0 {
+		return nil, errors.New("invalid response length checksum")
+	}
+	if length == 1 && buffer[5] == PN532_ERRORFRAME {
+		return nil, errors.New("application level error frame received")
+	}
+	if length < 2 || 6+length > len(buffer) {
+		return nil, errors.New("response frame exceeds the buffer")
+	}
+	var sum byte = 0
+	for _, b := range buffer[5 : 6+length] {
+		sum += b
+	}
+	if sum != 0 {
+		return nil, errors.New("invalid response data checksum")
+	}
+	if buffer[5] != PN532_PN532TOHOST || buffer[6] != command+1 {
+		return nil, errors.New("unexpected response code")
+	}
+	return buffer[7 : 5+length], nil
+}
+
 func (d *Device) readdata(buffer []byte) error {
 	rxBuffer := d.rxBuffer[:len(buffer)+1]
 	d.bus.Tx(d.address, nil, rxBuffer)
@@ -227,10 +373,12 @@ func (d *Device) i2cTuning() {
 }
 
 func (d *Device) FirmwareVersion() (FirmwareVersion, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	version := FirmwareVersion{}
 	buffer := d.buffer[:1]
 	buffer[0] = COMMAND_GETFIRMWAREVERSION
-	err := d.sendCommandCheckAck(buffer, 100*time.Millisecond)
+	err := d.sendCommandCheckAck(context.Background(), buffer, 100*time.Millisecond)
 	if err != nil {
 		return version, err
 	}
@@ -259,23 +407,74 @@ func (d *Device) FirmwareVersion() (FirmwareVersion, error) {
 	return version, nil
 }
 
+// Target describes an ISO/IEC 14443 type A card listed by InListPassiveTarget.
+type Target struct {
+	SensRes uint16 // SENS_RES (ATQA)
+	SelRes  uint8  // SEL_RES (SAK), tells the type of the card
+	UID     []byte // NFCID1
+}
+
+// ReadPassiveTargetID waits until a card is in range and returns its UID. A
+// timeout of 0 waits forever.
 func (d *Device) ReadPassiveTargetID(cardbaudrate uint8, timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, err := d.readPassiveTarget(context.Background(), cardbaudrate, timeout)
+	return target.UID, err
+}
+
+// ReadPassiveTargetIDContext waits until a card is in range and returns its
+// UID. Once the context is done the pending poll is aborted and the error of
+// the context is returned.
+func (d *Device) ReadPassiveTargetIDContext(ctx context.Context, cardbaudrate uint8) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, err := d.readPassiveTarget(ctx, cardbaudrate, 0)
+	return target.UID, err
+}
+
+// ReadPassiveTarget waits until a card is in range and returns its UID along
+// with the SENS_RES and SEL_RES. A timeout of 0 waits forever.
+func (d *Device) ReadPassiveTarget(cardbaudrate uint8, timeout time.Duration) (Target, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readPassiveTarget(context.Background(), cardbaudrate, timeout)
+}
+
+// ReadPassiveTargetContext waits until a card is in range like
+// ReadPassiveTarget or until the context is done.
+func (d *Device) ReadPassiveTargetContext(ctx context.Context, cardbaudrate uint8) (Target, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readPassiveTarget(ctx, cardbaudrate, 0)
+}
+
+func (d *Device) readPassiveTarget(ctx context.Context, cardbaudrate uint8, timeout time.Duration) (Target, error) {
 	buffer := d.buffer[:3]
 	buffer[0] = COMMAND_INLISTPASSIVETARGET
 	buffer[1] = 1 // limit this for one card at the moment
 	buffer[2] = cardbaudrate
 
-	if err := d.sendCommandCheckAck(buffer, timeout); err != nil {
-		return []byte{}, errors.Join(errors.New("Failed sendCommandCheckAck"), err)
+	if err := d.sendCommandCheckAck(ctx, buffer, timeout); err != nil {
+		return Target{UID: []byte{}}, errors.Join(errors.New("Failed sendCommandCheckAck"), err)
 	}
 
-	return d.ReadDetectedPassiveTargetID()
+	return d.readDetectedPassiveTarget()
 }
 
 func (d *Device) ReadDetectedPassiveTargetID() ([]byte, error) {
-	buffer := d.buffer[:20]
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	target, err := d.readDetectedPassiveTarget()
+	return target.UID, err
+}
+
+func (d *Device) readDetectedPassiveTarget() (Target, error) {
+	target := Target{UID: []byte{}}
+	// room for the longest NFCID of 10 bytes
+	buffer := d.buffer[:25]
 	if err := d.readdata(buffer); err != nil {
-		return []byte{}, err
+		return target, err
 	}
 	/* ISO14443A card response should be in the following format:
 
@@ -291,14 +490,22 @@ func (d *Device) ReadDetectedPassiveTargetID() ([]byte, error) {
 	*/
 	const b13 = 13
 	if buffer[7] != 1 {
-		return []byte{}, errors.New("invalid amount of cards detected")
+		return target, errors.New("invalid amount of cards detected")
 	}
 	var sense_res uint16 = uint16(buffer[9])
 
 	sense_res <<= 8
 	sense_res |= uint16(buffer[10])
 	nfcIDLen := int(buffer[12])
-	uid := buffer[b13 : b13+nfcIDLen]
-
-	return uid, nil
+	if b13+nfcIDLen > len(buffer) {
+		return target, errors.New("invalid NFCID length")
+	}
+	// the buffer is reused by the next command
+	uid := make([]byte, nfcIDLen)
+	copy(uid, buffer[b13:b13+nfcIDLen])
+
+	target.SensRes = sense_res
+	target.SelRes = buffer[11]
+	target.UID = uid
+	return target, nil
 }
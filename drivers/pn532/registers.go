@@ -0,0 +1,105 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Registers of the contactless interface unit (CIU). See the PN533 data
+// sheet 8.6, the PN532 uses the same CIU.
+const (
+	REGISTER_CIU_TXMODE     = 0x6302 // Bit 7 enables the CRC of transmitted frames
+	REGISTER_CIU_RXMODE     = 0x6303 // Bit 7 enables the CRC of received frames
+	REGISTER_CIU_STATUS2    = 0x6338 // Bit 3 reports the Crypto1 unit is on
+	REGISTER_CIU_BITFRAMING = 0x633D // Bits 2..0 hold the bits of the last byte to send
+)
+
+// ReadRegister reads a register of the PN532. See [2] 7.2.4.
+func (d *Device) ReadRegister(address uint16) (uint8, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readRegister(address)
+}
+
+func (d *Device) readRegister(address uint16) (uint8, error) {
+	buffer := d.buffer[:3]
+	buffer[0] = COMMAND_READREGISTER
+	buffer[1] = byte(address >> 8)
+	buffer[2] = byte(address)
+	response, err := d.exchange(context.Background(), buffer, d.buffer[:10], 100*time.Millisecond)
+	if err != nil {
+		return 0, err
+	}
+	if len(response) != 1 {
+		return 0, errors.New("invalid ReadRegister response")
+	}
+	return response[0], nil
+}
+
+// WriteRegister writes a register of the PN532. See [2] 7.2.5.
+func (d *Device) WriteRegister(address uint16, value uint8) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.writeRegister(address, value)
+}
+
+func (d *Device) writeRegister(address uint16, value uint8) error {
+	buffer := d.buffer[:4]
+	buffer[0] = COMMAND_WRITEREGISTER
+	buffer[1] = byte(address >> 8)
+	buffer[2] = byte(address)
+	buffer[3] = value
+	_, err := d.exchange(context.Background(), buffer, d.buffer[:10], 100*time.Millisecond)
+	return err
+}
+
+// updateRegister sets the bits of mask to the bits of value.
+func (d *Device) updateRegister(address uint16, mask uint8, value uint8) error {
+	current, err := d.readRegister(address)
+	if err != nil {
+		return err
+	}
+	return d.writeRegister(address, current&^mask|value&mask)
+}
+
+// InCommunicateThru sends a raw frame to the activated target and returns
+// its answer. Framing, CRC and Crypto1 are applied as configured in the CIU
+// registers. See [2] 7.3.9.
+func (d *Device) InCommunicateThru(data []byte, timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	response, err := d.inCommunicateThru(data, timeout)
+	return detach(response, err)
+}
+
+func (d *Device) inCommunicateThru(data []byte, timeout time.Duration) ([]byte, error) {
+	if len(data) > BUFFSIZE-9 {
+		return nil, errors.New("the given data exceeds the buffer")
+	}
+	buffer := d.buffer[:1+len(data)]
+	buffer[0] = COMMAND_INCOMMUNICATETHRU
+	copy(buffer[1:], data)
+	response, err := d.exchange(context.Background(), buffer, d.response[:], timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 {
+		return nil, errors.New("invalid InCommunicateThru response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return nil, err
+	}
+	return response[1:], nil
+}
+
+// crcA returns the ISO/IEC 14443-A CRC of the frame, LSB first.
+func crcA(data []byte) [2]byte {
+	crc := uint16(0x6363)
+	for _, b := range data {
+		b ^= byte(crc)
+		b ^= b << 4
+		crc = crc>>8 ^ uint16(b)<<8 ^ uint16(b)<<3 ^ uint16(b)>>4
+	}
+	return [2]byte{byte(crc), byte(crc >> 8)}
+}
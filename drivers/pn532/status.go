@@ -0,0 +1,71 @@
+package pn532
+
+import "strconv"
+
+// StatusError is the error code reported in the status byte of a PN532
+// response. See [2] 7.1 Error handling.
+type StatusError uint8
+
+// Status codes of the PN532
+const (
+	StatusTimeout             StatusError = 0x01 // The target has not answered
+	StatusCRC                 StatusError = 0x02 // A CRC error has been detected
+	StatusParity              StatusError = 0x03 // A parity error has been detected
+	StatusBitCount            StatusError = 0x04 // Erroneous bit count during anticollision
+	StatusFraming             StatusError = 0x05 // Framing error during MIFARE operation
+	StatusCollision           StatusError = 0x06 // Abnormal bit-collision
+	StatusBufferSize          StatusError = 0x07 // Communication buffer size insufficient
+	StatusRFBuffer            StatusError = 0x09 // RF buffer overflow
+	StatusRFField             StatusError = 0x0A // The RF field has not been switched on in time
+	StatusRFProtocol          StatusError = 0x0B // RF protocol error
+	StatusTemperature         StatusError = 0x0D // The internal temperature sensor detected overheating
+	StatusInternalBuffer      StatusError = 0x0E // Internal buffer overflow
+	StatusInvalidParameter    StatusError = 0x10 // Invalid parameter
+	StatusDEPCommand          StatusError = 0x12 // The DEP command received is not supported
+	StatusDataFormat          StatusError = 0x13 // Wrong data format or MIFARE/ISO14443-4 protocol error
+	StatusAuthentication      StatusError = 0x14 // MIFARE authentication error
+	StatusUIDCheckByte        StatusError = 0x23 // Wrong UID check byte (BCC)
+	StatusDEPState            StatusError = 0x25 // Invalid device state for this command
+	StatusOperationNotAllowed StatusError = 0x26 // Operation not allowed in this configuration
+	StatusCommandContext      StatusError = 0x27 // Command not acceptable in the current context
+	StatusReleased            StatusError = 0x29 // The target has been released by the initiator
+	StatusCardExchanged       StatusError = 0x2A // The card ID does not match, the card has been exchanged
+	StatusCardDisappeared     StatusError = 0x2B // The card has disappeared
+	StatusNFCIDMismatch       StatusError = 0x2C // NFCID3 mismatch during DEP
+	StatusOvercurrent         StatusError = 0x2D // An over-current event has been detected
+	StatusNADMissing          StatusError = 0x2E // NAD missing in DEP frame
+)
+
+// Status flags in the status byte beside the error code
+const (
+	StatusMI  = 0x40 // More information, the data is chained
+	StatusNAD = 0x80 // NAD is used
+)
+
+func (e StatusError) Error() string {
+	switch e {
+	case StatusTimeout:
+		return "pn532: target timeout"
+	case StatusCRC:
+		return "pn532: CRC error"
+	case StatusParity:
+		return "pn532: parity error"
+	case StatusAuthentication:
+		return "pn532: MIFARE authentication error"
+	case StatusDataFormat:
+		return "pn532: wrong data format"
+	case StatusReleased:
+		return "pn532: target released by the initiator"
+	case StatusCardDisappeared:
+		return "pn532: card disappeared"
+	}
+	return "pn532: status error 0x" + strconv.FormatUint(uint64(e), 16)
+}
+
+// checkStatus returns the error code of a status byte or nil.
+func checkStatus(status byte) error {
+	if code := status & 0x3F; code != 0 {
+		return StatusError(code)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Target mode flags used by TgInitAsTarget. See [2] 7.3.14 TgInitAsTarget.
+const (
+	TargetModePassiveOnly = 0x01 // Only accept a passive activation
+	TargetModeDEPOnly     = 0x02 // Only accept an activation in DEP mode
+	TargetModePICCOnly    = 0x04 // Only accept an activation as ISO/IEC 14443-4 PICC
+)
+
+// TargetConfig holds the parameters the PN532 uses to answer an initiator
+// while it acts as a target.
+type TargetConfig struct {
+	Mode    uint8   // Combination of the TargetMode flags
+	SensRes [2]byte // SENS_RES (ATQA), LSB first
+	NFCID1  [3]byte // NFCID1t, the PN532 always prepends 0x08 to the UID
+	SelRes  byte    // SEL_RES (SAK), 0x20 announces ISO/IEC 14443-4 compliance
+	// FeliCa holds NFCID2t (8 bytes), PAD (8 bytes) and the system code (2 bytes)
+	FeliCa [18]byte
+	NFCID3 [10]byte // NFCID3t used in the ATR_RES
+	// GeneralBytes are sent in the ATR_RES when activated in DEP mode
+	GeneralBytes []byte
+	// HistoricalBytes are sent in the ATS when activated as ISO/IEC 14443-4 PICC
+	HistoricalBytes []byte
+}
+
+// TargetActivation describes how the initiator has activated the PN532.
+type TargetActivation struct {
+	// Mode holds the baud rate (bits 6..4), whether the DEP protocol is used
+	// (bit 2) and the framing type (bits 1..0)
+	Mode uint8
+	// Command is the first command received from the initiator, like the
+	// ATR_REQ in DEP mode or the RATS in ISO/IEC 14443-4 mode
+	Command []byte
+}
+
+// TgInitAsTarget configures the PN532 as target and waits until an initiator
+// activates it. A timeout of 0 waits forever.
+func (d *Device) TgInitAsTarget(config *TargetConfig, timeout time.Duration) (TargetActivation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tgInitAsTarget(context.Background(), config, timeout)
+}
+
+// TgInitAsTargetContext configures the PN532 as target and waits until an
+// initiator activates it or the context is done.
+func (d *Device) TgInitAsTargetContext(ctx context.Context, config *TargetConfig) (TargetActivation, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tgInitAsTarget(ctx, config, 0)
+}
+
+func (d *Device) tgInitAsTarget(ctx context.Context, config *TargetConfig, timeout time.Duration) (TargetActivation, error) {
+	activation := TargetActivation{}
+	const fixedLen = 38
+	if fixedLen+len(config.GeneralBytes)+len(config.HistoricalBytes) > BUFFSIZE-8 {
+		return activation, errors.New("general and historical bytes exceed the buffer")
+	}
+	buffer := d.buffer[:fixedLen+len(config.GeneralBytes)+len(config.HistoricalBytes)]
+	buffer[0] = COMMAND_TGINITASTARGET
+	buffer[1] = config.Mode
+	copy(buffer[2:4], config.SensRes[:])
+	copy(buffer[4:7], config.NFCID1[:])
+	buffer[7] = config.SelRes
+	copy(buffer[8:26], config.FeliCa[:])
+	copy(buffer[26:36], config.NFCID3[:])
+	buffer[36] = byte(len(config.GeneralBytes))
+	n := 37 + copy(buffer[37:], config.GeneralBytes)
+	buffer[n] = byte(len(config.HistoricalBytes))
+	copy(buffer[n+1:], config.HistoricalBytes)
+
+	response, err := d.exchange(ctx, buffer, d.response[:], timeout)
+	if err != nil {
+		return activation, err
+	}
+	if len(response) < 1 {
+		return activation, errors.New("invalid TgInitAsTarget response")
+	}
+	activation.Mode = response[0]
+	activation.Command = make([]byte, len(response)-1)
+	copy(activation.Command, response[1:])
+	return activation, nil
+}
+
+// TgGetData returns the next data frame sent by the initiator in DEP or
+// ISO/IEC 14443-4 mode.
+func (d *Device) TgGetData(timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return detach(d.tgGetData(context.Background(), timeout))
+}
+
+// TgGetDataContext returns the next data frame sent by the initiator or the
+// error of the context once it is done.
+func (d *Device) TgGetDataContext(ctx context.Context) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return detach(d.tgGetData(ctx, 0))
+}
+
+func (d *Device) tgGetData(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	buffer := d.buffer[:1]
+	buffer[0] = COMMAND_TGGETDATA
+	response, err := d.exchange(ctx, buffer, d.response[:], timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 {
+		return nil, errors.New("invalid TgGetData response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return nil, err
+	}
+	return response[1:], nil
+}
+
+// TgSetData sends data back to the initiator in DEP or ISO/IEC 14443-4 mode.
+func (d *Device) TgSetData(data []byte, timeout time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.targetSend(COMMAND_TGSETDATA, data, timeout)
+}
+
+// TgGetInitiatorCommand returns the next raw command sent by the initiator
+// when the PN532 is not activated in DEP or ISO/IEC 14443-4 mode.
+func (d *Device) TgGetInitiatorCommand(timeout time.Duration) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return detach(d.tgGetInitiatorCommand(context.Background(), timeout))
+}
+
+// TgGetInitiatorCommandContext returns the next raw command sent by the
+// initiator or the error of the context once it is done.
+func (d *Device) TgGetInitiatorCommandContext(ctx context.Context) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return detach(d.tgGetInitiatorCommand(ctx, 0))
+}
+
+func (d *Device) tgGetInitiatorCommand(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	buffer := d.buffer[:1]
+	buffer[0] = COMMAND_TGGETINITIATORCOMMAND
+	response, err := d.exchange(ctx, buffer, d.response[:], timeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < 1 {
+		return nil, errors.New("invalid TgGetInitiatorCommand response")
+	}
+	if err := checkStatus(response[0]); err != nil {
+		return nil, err
+	}
+	return response[1:], nil
+}
+
+// TgResponseToInitiator sends a raw response to the command returned by
+// TgGetInitiatorCommand.
+func (d *Device) TgResponseToInitiator(data []byte, timeout time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.targetSend(COMMAND_TGRESPONSETOINITIATOR, data, timeout)
+}
+
+func (d *Device) targetSend(command byte, data []byte, timeout time.Duration) error {
+	if len(data) > BUFFSIZE-9 {
+		return errors.New("the given data exceeds the buffer")
+	}
+	buffer := d.buffer[:1+len(data)]
+	buffer[0] = command
+	copy(buffer[1:], data)
+	response, err := d.exchange(context.Background(), buffer, d.response[:], timeout)
+	if err != nil {
+		return err
+	}
+	if len(response) < 1 {
+		return errors.New("invalid target response")
+	}
+	return checkStatus(response[0])
+}
+
+// detach copies the data of a response out of the buffers of the device,
+// which are reused by the next command.
+func detach(data []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, data...), nil
+}
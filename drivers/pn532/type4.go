@@ -0,0 +1,214 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/graugans/tinygo-examples/drivers/ndef"
+)
+
+// Type 4 tag constants. See NFC Forum Type 4 Tag Technical Specification.
+const (
+	type4FileCC   = 0xE103 // File ID of the capability container
+	type4FileNDEF = 0xE104 // File ID of the NDEF file
+	// The maximum R-APDU data size which fits into a TgSetData frame
+	type4MaxLe = BUFFSIZE - 9 - 2
+	// The maximum C-APDU data size which fits into a TgGetData response
+	type4MaxLc = type4MaxLe - 5
+	// The default time a reader may take for its next command before the
+	// session is considered over
+	type4IdleTimeout = 5 * time.Second
+)
+
+// The NDEF tag application name, version 2.0
+var type4Application = [...]byte{0xD2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01}
+
+// Status words of the emulated tag
+var (
+	type4OK               = []byte{0x90, 0x00}
+	type4FileNotFound     = []byte{0x6A, 0x82}
+	type4WrongOffset      = []byte{0x6B, 0x00}
+	type4NotAllowed       = []byte{0x69, 0x82}
+	type4INSNotSupported  = []byte{0x6D, 0x00}
+	type4CLANotSupported  = []byte{0x6E, 0x00}
+	type4WrongLength      = []byte{0x67, 0x00}
+	type4ConditionsNotMet = []byte{0x69, 0x85}
+)
+
+// Type4Tag emulates a read-only NFC Forum Type 4 tag holding a single NDEF
+// message. The PN532 handles the anticollision and the RATS on its own, the
+// tag only has to answer the APDUs of the NDEF tag application.
+type Type4Tag struct {
+	dev      *Device
+	config   TargetConfig
+	cc       [15]byte
+	ndef     []byte // NLEN followed by the NDEF message
+	selected uint16 // The currently selected file
+	// The time a reader may take for its next command
+	idleTimeout time.Duration
+	response    [BUFFSIZE]byte
+}
+
+// NewType4TagMessage creates a Type 4 tag emulation serving the message.
+func NewType4TagMessage(device *Device, message *ndef.Message) (Type4Tag, error) {
+	encoded, err := message.Marshal(nil)
+	if err != nil {
+		return Type4Tag{}, err
+	}
+	return NewType4Tag(device, encoded)
+}
+
+// NewType4Tag creates a Type 4 tag emulation serving the given NDEF message.
+func NewType4Tag(device *Device, message []byte) (Type4Tag, error) {
+	if len(message) > 0xFFFE-2 {
+		return Type4Tag{}, errors.New("the NDEF message exceeds the NDEF file size")
+	}
+	ndef := make([]byte, 2+len(message))
+	ndef[0] = byte(len(message) >> 8)
+	ndef[1] = byte(len(message))
+	copy(ndef[2:], message)
+	tag := Type4Tag{
+		dev: device,
+		config: TargetConfig{
+			Mode:    TargetModePassiveOnly | TargetModePICCOnly,
+			SensRes: [2]byte{0x04, 0x00},
+			NFCID1:  [3]byte{0x12, 0x34, 0x56},
+			SelRes:  0x20,
+		},
+		cc: [...]byte{
+			0x00, 0x0F, // CCLEN
+			0x20,             // Mapping version 2.0
+			0x00, type4MaxLe, // MLe
+			0x00, type4MaxLc, // MLc
+			0x04, 0x06, // NDEF file control TLV
+			type4FileNDEF >> 8, type4FileNDEF & 0xFF,
+			byte(len(ndef) >> 8), byte(len(ndef)), // Maximum NDEF file size
+			0x00, // Read access granted
+			0xFF, // No write access
+		},
+		ndef:        ndef,
+		idleTimeout: type4IdleTimeout,
+	}
+	return tag, nil
+}
+
+// SetNFCID1 sets the 3 bytes of the UID, the PN532 prepends 0x08 to it.
+func (t *Type4Tag) SetNFCID1(id [3]byte) {
+	t.config.NFCID1 = id
+}
+
+// SetIdleTimeout sets the time a reader may take for its next command before
+// Serve considers the session over, 5 seconds by default. A timeout of 0 waits
+// as long as the reader stays in the field.
+func (t *Type4Tag) SetIdleTimeout(timeout time.Duration) {
+	t.idleTimeout = timeout
+}
+
+// Serve waits until a reader activates the tag and answers its commands
+// until the reader releases the tag, leaves the field or sends no command
+// within the idle timeout, see SetIdleTimeout. A timeout of 0 waits forever
+// for a reader.
+func (t *Type4Tag) Serve(timeout time.Duration) error {
+	return t.serve(context.Background(), timeout)
+}
+
+// ServeContext serves a reader like Serve. Waiting for the reader and the
+// answers to it are aborted once the context is done.
+func (t *Type4Tag) ServeContext(ctx context.Context) error {
+	return t.serve(ctx, 0)
+}
+
+func (t *Type4Tag) serve(ctx context.Context, timeout time.Duration) error {
+	t.dev.mu.Lock()
+	_, err := t.dev.tgInitAsTarget(ctx, &t.config, timeout)
+	t.dev.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	t.selected = 0
+	for {
+		err := t.answer(ctx)
+		var status StatusError
+		if errors.Is(err, ErrTimeout) || errors.As(err, &status) && (status == StatusReleased || status == StatusTimeout) {
+			// the reader is done or has gone away
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// answer receives the next APDU from the reader and sends the response.
+func (t *Type4Tag) answer(ctx context.Context) error {
+	t.dev.mu.Lock()
+	defer t.dev.mu.Unlock()
+	apdu, err := t.dev.tgGetData(ctx, t.idleTimeout)
+	if err != nil {
+		return err
+	}
+	return t.dev.targetSend(COMMAND_TGSETDATA, t.handle(apdu), time.Second)
+}
+
+// handle processes a single command APDU and returns the response APDU.
+func (t *Type4Tag) handle(apdu []byte) []byte {
+	if len(apdu) < 4 {
+		return type4WrongLength
+	}
+	if apdu[0] != 0x00 {
+		return type4CLANotSupported
+	}
+	switch apdu[1] {
+	case 0xA4: // SELECT
+		return t.selectFile(apdu)
+	case 0xB0: // READ BINARY
+		return t.readBinary(apdu)
+	case 0xD6: // UPDATE BINARY
+		return type4NotAllowed
+	}
+	return type4INSNotSupported
+}
+
+func (t *Type4Tag) selectFile(apdu []byte) []byte {
+	if len(apdu) < 5 || len(apdu) < 5+int(apdu[4]) {
+		return type4WrongLength
+	}
+	data := apdu[5 : 5+int(apdu[4])]
+	switch {
+	case apdu[2] == 0x04 && string(data) == string(type4Application[:]):
+		t.selected = 0
+		return type4OK
+	case apdu[2] == 0x00 && len(data) == 2:
+		file := uint16(data[0])<<8 | uint16(data[1])
+		if file != type4FileCC && file != type4FileNDEF {
+			return type4FileNotFound
+		}
+		t.selected = file
+		return type4OK
+	}
+	return type4FileNotFound
+}
+
+func (t *Type4Tag) readBinary(apdu []byte) []byte {
+	var file []byte
+	switch t.selected {
+	case type4FileCC:
+		file = t.cc[:]
+	case type4FileNDEF:
+		file = t.ndef
+	default:
+		return type4ConditionsNotMet
+	}
+	offset := int(apdu[2])<<8 | int(apdu[3])
+	if offset > len(file) {
+		return type4WrongOffset
+	}
+	le := type4MaxLe
+	if len(apdu) > 4 && apdu[4] != 0 && int(apdu[4]) < le {
+		le = int(apdu[4])
+	}
+	n := copy(t.response[:le], file[offset:])
+	n += copy(t.response[n:], type4OK)
+	return t.response[:n]
+}
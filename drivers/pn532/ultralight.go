@@ -0,0 +1,133 @@
+package pn532
+
+import (
+	"errors"
+	"time"
+)
+
+// UltralightPageSize is the size of a page of MIFARE Ultralight and NTAG21x
+// tags, the NFC Forum Type 2 tags.
+const UltralightPageSize = 4
+
+// The pages returned by a single READ
+const ultralightReadPages = 4
+
+// Ultralight accesses MIFARE Ultralight and NTAG21x tags. See NXP NTAG213/215/216
+// data sheet 10 NTAG commands.
+type Ultralight struct {
+	dev *Device
+	// total pages of the tag, 0 until identified by GET_VERSION
+	pages uint8
+	// bytes of user memory, known once pages is
+	userMemory int
+	// pages locked by a dynamic lock bit, 0 without dynamic lock bits
+	pagesPerLockBit uint8
+	// the tag is a NTAG21x, known once pages is
+	ntag bool
+	// the tag refused GET_VERSION
+	noVersion bool
+}
+
+// NewUltralight returns an Ultralight accessing the tag listed by the device.
+func NewUltralight(device *Device) Ultralight {
+	return Ultralight{dev: device}
+}
+
+// ReadPages reads the 4 pages starting at page. Reading past the last page
+// of the tag rolls over to page 0.
+func (u *Ultralight) ReadPages(page uint8) ([]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.readPages(page)
+}
+
+func (u *Ultralight) readPages(page uint8) ([]byte, error) {
+	command := [...]byte{MIFARE_CMD_READ, page}
+	response, err := u.dev.inDataExchange(command[:], 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < ultralightReadPages*UltralightPageSize {
+		return nil, errors.New("invalid Ultralight READ response")
+	}
+	return append([]byte{}, response[:ultralightReadPages*UltralightPageSize]...), nil
+}
+
+// WritePage writes a single page.
+func (u *Ultralight) WritePage(page uint8, data []byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.writePage(page, data)
+}
+
+func (u *Ultralight) writePage(page uint8, data []byte) error {
+	if len(data) != UltralightPageSize {
+		return errors.New("a page needs 4 bytes")
+	}
+	command := [2 + UltralightPageSize]byte{MIFARE_ULTRALIGHT_CMD_WRITE, page}
+	copy(command[2:], data)
+	_, err := u.dev.inDataExchange(command[:], 100*time.Millisecond)
+	return err
+}
+
+// CompatibilityWrite writes a single page using the MIFARE Classic WRITE
+// command, for readers only supporting it. The data is padded to 16 bytes,
+// the tag only stores the first 4 bytes.
+func (u *Ultralight) CompatibilityWrite(page uint8, data []byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	if len(data) != UltralightPageSize {
+		return errors.New("a page needs 4 bytes")
+	}
+	command := [2 + MifareClassicBlockSize]byte{MIFARE_CMD_WRITE, page}
+	copy(command[2:], data)
+	_, err := u.dev.inDataExchange(command[:], 100*time.Millisecond)
+	return err
+}
+
+// ReadPageRange reads the pages first to last, both included.
+func (u *Ultralight) ReadPageRange(first uint8, last uint8) ([]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.readPageRange(first, last)
+}
+
+func (u *Ultralight) readPageRange(first uint8, last uint8) ([]byte, error) {
+	if last < first {
+		return nil, errors.New("invalid page range")
+	}
+	length := (int(last) - int(first) + 1) * UltralightPageSize
+	data := make([]byte, 0, length+ultralightReadPages*UltralightPageSize)
+	for page := int(first); page <= int(last); page += ultralightReadPages {
+		pages, err := u.readPages(uint8(page))
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, pages...)
+	}
+	return data[:length], nil
+}
+
+// WritePageRange writes the data to the pages starting at first. The data
+// must cover whole pages.
+func (u *Ultralight) WritePageRange(first uint8, data []byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.writePageRange(first, data)
+}
+
+func (u *Ultralight) writePageRange(first uint8, data []byte) error {
+	if len(data)%UltralightPageSize != 0 {
+		return errors.New("the data must cover whole pages")
+	}
+	if int(first)+len(data)/UltralightPageSize > 256 {
+		return errors.New("the data exceeds the last page")
+	}
+	for page := int(first); len(data) > 0; page++ {
+		if err := u.writePage(uint8(page), data[:UltralightPageSize]); err != nil {
+			return err
+		}
+		data = data[UltralightPageSize:]
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package pn532
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+// Pages of the MIFARE Ultralight C configuration. See NXP MF0ICU2 data sheet
+// 7.5.
+const (
+	ultralightCAuth0Page = 0x2A // Byte 0 holds the first page requiring authentication
+	ultralightCAuth1Page = 0x2B // Bit 0 of byte 0 restricts only writes if set
+	ultralightCKeyPage   = 0x2C // 4 pages holding the 16 byte key
+)
+
+// UltralightCKeySize is the size of the 2 key 3DES key of the Ultralight C.
+const UltralightCKeySize = 16
+
+// UltralightCDefaultKey is the key the tags are shipped with, "BREAKMEIFYOUCAN!"
+// with the bytes of each half reversed.
+var UltralightCDefaultKey = [UltralightCKeySize]byte{
+	0x49, 0x45, 0x4D, 0x4B, 0x41, 0x45, 0x52, 0x42,
+	0x21, 0x4E, 0x41, 0x43, 0x55, 0x4F, 0x59, 0x46,
+}
+
+// ErrUltralightCAuth is returned if the tag refuses the key or answers with
+// a wrong challenge.
+var ErrUltralightCAuth = errors.New("the 3DES authentication failed")
+
+// newUltralightCCipher returns the 3DES cipher with the keys K1, K2, K1.
+func newUltralightCCipher(key [UltralightCKeySize]byte) (cipher.Block, error) {
+	var tripleKey [24]byte
+	copy(tripleKey[:], key[:])
+	copy(tripleKey[16:], key[:8])
+	return des.NewTripleDESCipher(tripleKey[:])
+}
+
+// rotateLeft returns the 8 byte random number rotated left by one byte.
+func rotateLeft(rnd []byte) []byte {
+	return append(append([]byte{}, rnd[1:]...), rnd[0])
+}
+
+// Authenticate3DES runs the mutual 3DES authentication of the Ultralight C.
+// Both sides prove the knowledge of the key by exchanging the encrypted
+// random numbers RndA and RndB in CBC mode. See NXP MF0ICU2 data sheet 7.5.5.
+func (u *Ultralight) Authenticate3DES(key [UltralightCKeySize]byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	block, err := newUltralightCCipher(key)
+	if err != nil {
+		return err
+	}
+	// the tag answers with ek(RndB)
+	command := [...]byte{MIFARE_ULTRALIGHTC_CMD_AUTHENTICATE, 0x00}
+	response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+	if err != nil {
+		return u.authError(err)
+	}
+	if len(response) != 1+des.BlockSize || response[0] != MIFARE_ULTRALIGHTC_CMD_CONTINUE {
+		return ErrUltralightCAuth
+	}
+	encryptedRndB := append([]byte{}, response[1:]...)
+	rndB := make([]byte, des.BlockSize)
+	var iv [des.BlockSize]byte
+	cipher.NewCBCDecrypter(block, iv[:]).CryptBlocks(rndB, encryptedRndB)
+	// answer with ek(RndA || RndB'), chained to the received block
+	token := make([]byte, 2*des.BlockSize)
+	if _, err := rand.Read(token[:des.BlockSize]); err != nil {
+		return err
+	}
+	rndA := append([]byte{}, token[:des.BlockSize]...)
+	copy(token[des.BlockSize:], rotateLeft(rndB))
+	cipher.NewCBCEncrypter(block, encryptedRndB).CryptBlocks(token, token)
+	var answer [1 + 2*des.BlockSize]byte
+	answer[0] = MIFARE_ULTRALIGHTC_CMD_CONTINUE
+	copy(answer[1:], token)
+	response, err = u.dev.inCommunicateThru(answer[:], 100*time.Millisecond)
+	if err != nil {
+		return u.authError(err)
+	}
+	if len(response) != 1+des.BlockSize || response[0] != 0x00 {
+		return ErrUltralightCAuth
+	}
+	// the tag proves the key with ek(RndA'), chained to the sent token
+	rndAReceived := make([]byte, des.BlockSize)
+	cipher.NewCBCDecrypter(block, token[des.BlockSize:]).CryptBlocks(rndAReceived, response[1:])
+	if !bytes.Equal(rndAReceived, rotateLeft(rndA)) {
+		return ErrUltralightCAuth
+	}
+	return nil
+}
+
+// authError maps the NAK of a refused key to ErrUltralightCAuth.
+func (u *Ultralight) authError(err error) error {
+	var status StatusError
+	if errors.As(err, &status) {
+		return ErrUltralightCAuth
+	}
+	return err
+}
+
+// Write3DESKey writes the key to the key pages. The key pages are never
+// readable. Once the protection is enabled the tag needs Authenticate3DES
+// with the current key first.
+func (u *Ultralight) Write3DESKey(key [UltralightCKeySize]byte) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	// each half of the key is stored with its bytes reversed
+	var pages [UltralightCKeySize]byte
+	for i := 0; i < 8; i++ {
+		pages[i] = key[7-i]
+		pages[8+i] = key[15-i]
+	}
+	for i := uint8(0); i < 4; i++ {
+		if err := u.writePage(ultralightCKeyPage+i, pages[i*UltralightPageSize:(i+1)*UltralightPageSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set3DESProtection requires the authentication for the pages starting at
+// auth0, only for writing if writeOnly is set. An auth0 of 0x30 or above
+// disables the protection.
+func (u *Ultralight) Set3DESProtection(auth0 uint8, writeOnly bool) error {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	var auth1 uint8
+	if writeOnly {
+		auth1 = 0x01
+	}
+	page := [UltralightPageSize]byte{auth1}
+	if err := u.writePage(ultralightCAuth1Page, page[:]); err != nil {
+		return err
+	}
+	page[0] = auth0
+	return u.writePage(ultralightCAuth0Page, page[:])
+}
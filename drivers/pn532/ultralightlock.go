@@ -0,0 +1,239 @@
+package pn532
+
+import "errors"
+
+// Pages of the Type 2 tag memory. See NFC Forum Type 2 Tag Technical
+// Specification 1.0 2.2.
+const (
+	ultralightLockPage       = 2  // Bytes 2 and 3 hold the static lock bytes
+	ultralightOTPPage        = 3  // One time programmable, the capability container on NTAG
+	ultralightStaticLast     = 15 // Last page covered by the static lock bits
+	ultralightDynamicFirst   = 16 // First page covered by the dynamic lock bits
+	ultralightDynamicLockLen = 3
+)
+
+// ErrNotConfirmed is returned by operations which can not be undone if they
+// are called without confirmation.
+var ErrNotConfirmed = errors.New("the irreversible operation was not confirmed")
+
+// UltralightLocks holds the lock bytes of the tag. Lock bits are only ever
+// set, a set lock bit makes its pages read-only for good.
+type UltralightLocks struct {
+	// Static holds the bytes 2 and 3 of page 2. Bit n of the 16 bit value
+	// Static[0] | Static[1]<<8 locks page n for n from 3 to 15, bits 0 to 2
+	// are the block-lock bits freezing the lock bits themselves.
+	Static [2]byte
+	// Dynamic holds the dynamic lock bytes on the page in front of the
+	// configuration pages. Bit n of Dynamic[0] | Dynamic[1]<<8 locks a group
+	// of pages starting at page 16, Dynamic[2] holds the block-lock bits.
+	Dynamic [ultralightDynamicLockLen]byte
+}
+
+// UltralightLockPlan describes lock bits to set, see PlanLock.
+type UltralightLockPlan struct {
+	// Locks holds the lock bits to set
+	Locks UltralightLocks
+	// Pages lists the pages becoming read-only, which may exceed the
+	// requested range as a lock bit covers a group of pages.
+	Pages []uint8
+}
+
+// dynamicLockPage returns the page of the dynamic lock bytes, 0 if the tag
+// has none. Tags not identified by GET_VERSION are handled as having only the
+// static lock bytes. The lock of the device must be held.
+func (u *Ultralight) dynamicLockPage() (uint8, error) {
+	page, err := u.configPage()
+	if err == ErrNoVersion || err == ErrUnknownTag {
+		return 0, nil
+	}
+	if err != nil || u.pagesPerLockBit == 0 {
+		return 0, err
+	}
+	return page - 1, nil
+}
+
+// lockedPages returns the pages the lock bits make read-only, in ascending
+// order. The dynamic lock bits are ignored for a dynamic lock page of 0.
+func (u *Ultralight) lockedPages(locks UltralightLocks, dynamicPage uint8) []uint8 {
+	var pages []uint8
+	static := uint16(locks.Static[0]) | uint16(locks.Static[1])<<8
+	for page := uint8(ultralightOTPPage); page <= ultralightStaticLast; page++ {
+		if static>>page&1 != 0 {
+			pages = append(pages, page)
+		}
+	}
+	if dynamicPage == 0 {
+		return pages
+	}
+	dynamic := uint16(locks.Dynamic[0]) | uint16(locks.Dynamic[1])<<8
+	for page := int(ultralightDynamicFirst); page < int(dynamicPage); page++ {
+		bit := (page - ultralightDynamicFirst) / int(u.pagesPerLockBit)
+		if bit < 16 && dynamic>>bit&1 != 0 {
+			pages = append(pages, uint8(page))
+		}
+	}
+	return pages
+}
+
+// ReadLocks reads the static lock bytes and, for a tag identified by
+// GetVersion, the dynamic lock bytes.
+func (u *Ultralight) ReadLocks() (UltralightLocks, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	dynamicPage, err := u.dynamicLockPage()
+	if err != nil {
+		return UltralightLocks{}, err
+	}
+	return u.readLocks(dynamicPage)
+}
+
+// readLocks reads the dynamic lock bytes only for a dynamic lock page other
+// than 0. The lock of the device must be held.
+func (u *Ultralight) readLocks(dynamicPage uint8) (UltralightLocks, error) {
+	locks := UltralightLocks{}
+	pages, err := u.readPages(ultralightLockPage)
+	if err != nil {
+		return locks, err
+	}
+	copy(locks.Static[:], pages[2:4])
+	if dynamicPage == 0 {
+		return locks, nil
+	}
+	if pages, err = u.readPages(dynamicPage); err != nil {
+		return locks, err
+	}
+	copy(locks.Dynamic[:], pages)
+	return locks, nil
+}
+
+// LockedPages returns the pages already read-only by their lock bits.
+func (u *Ultralight) LockedPages() ([]uint8, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	dynamicPage, err := u.dynamicLockPage()
+	if err != nil {
+		return nil, err
+	}
+	locks, err := u.readLocks(dynamicPage)
+	if err != nil {
+		return nil, err
+	}
+	return u.lockedPages(locks, dynamicPage), nil
+}
+
+// PlanLock computes the lock bits making the pages first to last read-only,
+// without touching the tag. Only the pages from 3 up to the user memory end
+// can be locked, pages beyond 15 only on tags identified by GetVersion. The
+// plan lists the pages not yet locked which the lock bits would make
+// read-only, review it before calling Lock.
+func (u *Ultralight) PlanLock(first uint8, last uint8) (UltralightLockPlan, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	plan := UltralightLockPlan{}
+	if last < first || first < ultralightOTPPage {
+		return plan, errors.New("invalid page range")
+	}
+	// only pages beyond the static lock bits need the tag to be identified
+	var dynamicPage uint8
+	if last > ultralightStaticLast {
+		var err error
+		if dynamicPage, err = u.dynamicLockPage(); err != nil {
+			return plan, err
+		}
+		if dynamicPage == 0 || last >= dynamicPage {
+			return plan, errors.New("the pages can not be locked")
+		}
+	}
+	current, err := u.readLocks(dynamicPage)
+	if err != nil {
+		return plan, err
+	}
+	for page := int(first); page <= int(last); page++ {
+		if page <= ultralightStaticLast {
+			plan.Locks.Static[page/8] |= 1 << (page % 8)
+			continue
+		}
+		bit := (page - ultralightDynamicFirst) / int(u.pagesPerLockBit)
+		plan.Locks.Dynamic[bit/8] |= 1 << (bit % 8)
+	}
+	before := u.lockedPages(current, dynamicPage)
+	combined := current
+	for i := range combined.Static {
+		combined.Static[i] |= plan.Locks.Static[i]
+	}
+	for i := range combined.Dynamic {
+		combined.Dynamic[i] |= plan.Locks.Dynamic[i]
+	}
+	after := u.lockedPages(combined, dynamicPage)
+	locked := make(map[uint8]bool, len(before))
+	for _, page := range before {
+		locked[page] = true
+	}
+	for _, page := range after {
+		if !locked[page] {
+			plan.Pages = append(plan.Pages, page)
+		}
+	}
+	return plan, nil
+}
+
+// Lock sets the lock bits of the plan. This can not be undone, so it is
+// refused unless confirm is set.
+func (u *Ultralight) Lock(plan UltralightLockPlan, confirm bool) error {
+	if !confirm {
+		return ErrNotConfirmed
+	}
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	if plan.Locks.Static != [2]byte{} {
+		// bytes 0 and 1 of page 2 are not writable, the tag ORs the lock bits
+		pages, err := u.readPages(ultralightLockPage)
+		if err != nil {
+			return err
+		}
+		page := [UltralightPageSize]byte{pages[0], pages[1], plan.Locks.Static[0], plan.Locks.Static[1]}
+		if err := u.writePage(ultralightLockPage, page[:]); err != nil {
+			return err
+		}
+	}
+	if plan.Locks.Dynamic != [ultralightDynamicLockLen]byte{} {
+		dynamicPage, err := u.dynamicLockPage()
+		if err != nil {
+			return err
+		}
+		if dynamicPage == 0 {
+			return errors.New("the tag has no dynamic lock bits")
+		}
+		page := [UltralightPageSize]byte{plan.Locks.Dynamic[0], plan.Locks.Dynamic[1], plan.Locks.Dynamic[2]}
+		if err := u.writePage(dynamicPage, page[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadOTP reads the one time programmable page 3.
+func (u *Ultralight) ReadOTP() ([UltralightPageSize]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	var otp [UltralightPageSize]byte
+	pages, err := u.readPages(ultralightOTPPage)
+	if err != nil {
+		return otp, err
+	}
+	copy(otp[:], pages)
+	return otp, nil
+}
+
+// WriteOTP sets bits of the one time programmable page 3. The tag ORs the
+// bits with the current content, set bits can never be cleared, so it is
+// refused unless confirm is set. On NTAG the page holds the capability
+// container.
+func (u *Ultralight) WriteOTP(bits [UltralightPageSize]byte, confirm bool) error {
+	if !confirm {
+		return ErrNotConfirmed
+	}
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.writePage(ultralightOTPPage, bits[:])
+}
@@ -0,0 +1,114 @@
+package pn532
+
+import (
+	"errors"
+
+	"github.com/graugans/tinygo-examples/drivers/ndef"
+)
+
+// The NDEF mapping of Type 2 tags. See NFC Forum Type 2 Tag Technical
+// Specification 1.0 2.3 and 2.4.
+const (
+	type2MagicNumber = 0xE1 // Byte 0 of the capability container
+	type2Version     = 0x10 // Mapping version 1.0
+	type2DataPage    = 4    // First page of the data area
+	type2TLVNull     = 0x00
+	type2TLVNDEF     = 0x03
+	type2TLVEnd      = 0xFE
+)
+
+// ErrNoNDEF is returned for a tag not formatted for NDEF or without NDEF
+// message.
+var ErrNoNDEF = errors.New("the tag holds no NDEF message")
+
+// dataAreaSize reads the capability container and returns the size of the
+// data area in bytes. The lock of the device must be held.
+func (u *Ultralight) dataAreaSize() (int, error) {
+	pages, err := u.readPages(ultralightOTPPage)
+	if err != nil {
+		return 0, err
+	}
+	if pages[0] != type2MagicNumber || pages[1]>>4 != type2Version>>4 {
+		return 0, ErrNoNDEF
+	}
+	return int(pages[2]) * 8, nil
+}
+
+// ReadNDEF reads the NDEF message TLV of a Type 2 tag and decodes it.
+func (u *Ultralight) ReadNDEF() (*ndef.Message, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	size, err := u.dataAreaSize()
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, ErrNoNDEF
+	}
+	data, err := u.readPageRange(type2DataPage, uint8(type2DataPage+(size-1)/UltralightPageSize))
+	if err != nil {
+		return nil, err
+	}
+	// skip the lock and memory control TLVs in front of the NDEF TLV
+	for len(data) > 0 {
+		tag := data[0]
+		if tag == type2TLVNull {
+			data = data[1:]
+			continue
+		}
+		if tag == type2TLVEnd || len(data) < 2 {
+			break
+		}
+		length, header := int(data[1]), 2
+		if data[1] == 0xFF {
+			if len(data) < 4 {
+				break
+			}
+			length, header = int(data[2])<<8|int(data[3]), 4
+		}
+		if len(data) < header+length {
+			return nil, errors.New("the TLV exceeds the data area")
+		}
+		if tag == type2TLVNDEF {
+			if length == 0 {
+				break
+			}
+			return ndef.Parse(data[header : header+length])
+		}
+		data = data[header+length:]
+	}
+	return nil, ErrNoNDEF
+}
+
+// WriteNDEF writes the message as NDEF message TLV followed by the
+// terminator TLV to the start of the data area, which must be formatted for
+// NDEF. Lock and memory control TLVs placed there are overwritten.
+func (u *Ultralight) WriteNDEF(message *ndef.Message) error {
+	encoded, err := message.Marshal(nil)
+	if err != nil {
+		return err
+	}
+	var tlv []byte
+	if len(encoded) < 0xFF {
+		tlv = append(tlv, type2TLVNDEF, byte(len(encoded)))
+	} else if len(encoded) <= 0xFFFE {
+		tlv = append(tlv, type2TLVNDEF, 0xFF, byte(len(encoded)>>8), byte(len(encoded)))
+	} else {
+		return errors.New("the NDEF message exceeds the TLV length")
+	}
+	tlv = append(tlv, encoded...)
+	tlv = append(tlv, type2TLVEnd)
+	for len(tlv)%UltralightPageSize != 0 {
+		tlv = append(tlv, type2TLVNull)
+	}
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	size, err := u.dataAreaSize()
+	if err != nil {
+		return err
+	}
+	if len(tlv) > size {
+		return errors.New("the NDEF message exceeds the data area")
+	}
+	return u.writePageRange(type2DataPage, tlv)
+}
@@ -0,0 +1,175 @@
+package pn532
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// UltralightVersion is the answer to GET_VERSION. See NXP NTAG213/215/216
+// data sheet 10.1 GET_VERSION.
+type UltralightVersion struct {
+	Vendor         uint8 // 0x04 for NXP
+	ProductType    uint8 // 0x03 MIFARE Ultralight, 0x04 NTAG
+	ProductSubtype uint8
+	MajorVersion   uint8
+	MinorVersion   uint8
+	StorageSize    uint8 // 2^n bytes, bit 0 set if the size is between 2^n and 2^(n+1)
+	Protocol       uint8 // 0x03 for ISO/IEC 14443-3
+}
+
+// Known products
+const (
+	ultralightProductUltralight = 0x03
+	ultralightProductNTAG       = 0x04
+)
+
+// ultralightProduct describes a known tag identified by its version.
+type ultralightProduct struct {
+	productType uint8
+	storageSize uint8
+	name        string
+	userMemory  int   // bytes of user memory
+	pages       uint8 // total pages
+	// pages locked by each dynamic lock bit, 0 if the dynamic lock bits
+	// are not supported
+	pagesPerLockBit uint8
+}
+
+var ultralightProducts = []ultralightProduct{
+	{ultralightProductUltralight, 0x0B, "MIFARE Ultralight EV1 MF0UL11", 48, 20, 0},
+	{ultralightProductUltralight, 0x0E, "MIFARE Ultralight EV1 MF0UL21", 128, 41, 0},
+	{ultralightProductNTAG, 0x0B, "NTAG210", 48, 20, 0},
+	{ultralightProductNTAG, 0x0E, "NTAG212", 128, 41, 0},
+	{ultralightProductNTAG, 0x0F, "NTAG213", 144, 45, 2},
+	{ultralightProductNTAG, 0x11, "NTAG215", 504, 135, 16},
+	{ultralightProductNTAG, 0x13, "NTAG216", 888, 231, 16},
+}
+
+func (v UltralightVersion) product() (ultralightProduct, bool) {
+	if v.Vendor != 0x04 {
+		return ultralightProduct{}, false
+	}
+	for _, product := range ultralightProducts {
+		if product.productType == v.ProductType && product.storageSize == v.StorageSize {
+			return product, true
+		}
+	}
+	return ultralightProduct{}, false
+}
+
+// Name returns the product name, "unknown" for a tag not known.
+func (v UltralightVersion) Name() string {
+	if product, ok := v.product(); ok {
+		return product.name
+	}
+	return "unknown"
+}
+
+// StorageBytes returns the storage size announced by the tag. A size between
+// two powers of two is returned as the lower one.
+func (v UltralightVersion) StorageBytes() int {
+	return 1 << (v.StorageSize >> 1)
+}
+
+// UserMemory returns the bytes of user memory of a known tag, 0 otherwise.
+func (v UltralightVersion) UserMemory() int {
+	product, _ := v.product()
+	return product.userMemory
+}
+
+// Pages returns the total number of pages of a known tag, 0 otherwise.
+func (v UltralightVersion) Pages() uint8 {
+	product, _ := v.product()
+	return product.pages
+}
+
+// PagesPerLockBit returns the pages locked by each dynamic lock bit of a
+// known tag, 0 if the tag has no dynamic lock bits the driver supports.
+func (v UltralightVersion) PagesPerLockBit() uint8 {
+	product, _ := v.product()
+	return product.pagesPerLockBit
+}
+
+// IsNTAG reports whether the tag is a NXP NTAG21x.
+func (v UltralightVersion) IsNTAG() bool {
+	return v.Vendor == 0x04 && v.ProductType == ultralightProductNTAG
+}
+
+// OriginalityKey returns the public key NXP signs the UID of the tag with,
+// nil for tags of other vendors or products.
+func (v UltralightVersion) OriginalityKey() []byte {
+	if v.Vendor != 0x04 {
+		return nil
+	}
+	switch v.ProductType {
+	case ultralightProductNTAG:
+		return NTAGOriginalityKey
+	case ultralightProductUltralight:
+		return UltralightEV1OriginalityKey
+	}
+	return nil
+}
+
+// ErrNoVersion is returned by tags not supporting GET_VERSION, like the
+// first generation of MIFARE Ultralight and the Ultralight C.
+var ErrNoVersion = errors.New("the tag does not support GET_VERSION")
+
+// GetVersion identifies the tag. MIFARE Ultralight tags of the first
+// generation and Ultralight C tags do not support the command, ErrNoVersion is
+// returned for them.
+func (u *Ultralight) GetVersion() (UltralightVersion, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	return u.getVersion()
+}
+
+// getVersion selects the tag again if it refused the command, as it halts
+// then. The lock of the device must be held.
+func (u *Ultralight) getVersion() (UltralightVersion, error) {
+	command := [...]byte{NTAG_CMD_GET_VERSION}
+	response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+	var status StatusError
+	if errors.As(err, &status) || (err == nil && (len(response) != 8 || response[0] != 0x00)) {
+		if err := u.reselect(); err != nil {
+			return UltralightVersion{}, err
+		}
+		return UltralightVersion{}, ErrNoVersion
+	}
+	if err != nil {
+		return UltralightVersion{}, err
+	}
+	return UltralightVersion{
+		Vendor:         response[1],
+		ProductType:    response[2],
+		ProductSubtype: response[3],
+		MajorVersion:   response[4],
+		MinorVersion:   response[5],
+		StorageSize:    response[6],
+		Protocol:       response[7],
+	}, nil
+}
+
+// ReadSignature reads the 32 byte ECC originality signature of the UID. Use
+// VerifyOriginalitySignature with the key returned by
+// UltralightVersion.OriginalityKey to check it.
+func (u *Ultralight) ReadSignature() ([]byte, error) {
+	u.dev.mu.Lock()
+	defer u.dev.mu.Unlock()
+	command := [...]byte{NTAG_CMD_READ_SIG, 0x00}
+	response, err := u.dev.inCommunicateThru(command[:], 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	if len(response) != 32 {
+		return nil, errors.New("invalid READ_SIG response")
+	}
+	return append([]byte{}, response...), nil
+}
+
+// reselect activates the tag again after it refused a command. The lock of
+// the device must be held.
+func (u *Ultralight) reselect() error {
+	_, err := u.dev.readPassiveTarget(context.Background(), MIFARE_ISO14443A, 100*time.Millisecond)
+	return err
+}
@@ -0,0 +1,307 @@
+// Package snep implements the NFC Forum Simple NDEF Exchange Protocol on top
+// of a LLCP data link connection. Phones use it to push NDEF messages to a
+// reader and to pull them from it.
+//
+// [1] NFC Forum Simple NDEF Exchange Protocol Technical Specification 1.0
+package snep
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/graugans/tinygo-examples/drivers/llcp"
+)
+
+// The service name of the default SNEP server
+const ServiceName = "urn:nfc:sn:snep"
+
+// Version 1.0 of SNEP
+const Version = 0x10
+
+// The size of the message header: version, code and length
+const headerSize = 6
+
+// The default limit for received messages
+const DefaultMaxMessageSize = 1024
+
+// Request codes. See [1] 3.1.
+const (
+	RequestContinue = 0x00
+	RequestGet      = 0x01
+	RequestPut      = 0x02
+	RequestReject   = 0x7F
+)
+
+// ResponseError is a response code other than success. Handlers return it to
+// choose the response sent to the client.
+type ResponseError uint8
+
+// Response codes. See [1] 3.2.
+const (
+	ResponseContinue       ResponseError = 0x80
+	ResponseSuccess        ResponseError = 0x81
+	ResponseNotFound       ResponseError = 0xC0
+	ResponseExcessData     ResponseError = 0xC1
+	ResponseBadRequest     ResponseError = 0xC2
+	ResponseNotImplemented ResponseError = 0xE0
+	ResponseUnsupported    ResponseError = 0xE1
+	ResponseReject         ResponseError = 0xFF
+)
+
+func (e ResponseError) Error() string {
+	switch e {
+	case ResponseNotFound:
+		return "snep: not found"
+	case ResponseExcessData:
+		return "snep: excess data"
+	case ResponseBadRequest:
+		return "snep: bad request"
+	case ResponseNotImplemented:
+		return "snep: not implemented"
+	case ResponseUnsupported:
+		return "snep: unsupported version"
+	case ResponseReject:
+		return "snep: rejected"
+	}
+	return "snep: response 0x" + strconv.FormatUint(uint64(e), 16)
+}
+
+var (
+	ErrInvalidMessage = errors.New("snep: invalid message")
+	ErrTooLarge       = errors.New("snep: message exceeds the maximum message size")
+)
+
+// Conn is the data link connection SNEP runs on, usually a *llcp.Conn.
+type Conn interface {
+	io.ReadWriter
+	MIU() int
+}
+
+// Handler serves the requests received by a server. Returning a
+// ResponseError selects the response code, any other error answers with
+// ResponseBadRequest.
+type Handler interface {
+	// Put receives a NDEF message pushed by the client.
+	Put(ndef []byte) error
+	// Get returns the NDEF message requested by the client. The response must
+	// not exceed the acceptable length.
+	Get(request []byte, acceptable uint32) ([]byte, error)
+}
+
+// endpoint implements the fragmentation shared by client and server.
+type endpoint struct {
+	conn    Conn
+	max     int
+	message []byte
+}
+
+// readMessage receives a complete message. A fragmented message is
+// acknowledged with the continue code. See [1] 5.1.
+func (e *endpoint) readMessage(continueCode, rejectCode byte) (byte, []byte, error) {
+	if cap(e.message) < e.conn.MIU()+headerSize {
+		e.message = make([]byte, e.conn.MIU()+headerSize)
+	}
+	buffer := e.message[:cap(e.message)]
+	n, err := e.conn.Read(buffer)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n < headerSize {
+		return 0, nil, ErrInvalidMessage
+	}
+	if buffer[0]>>4 != Version>>4 {
+		return buffer[1], nil, ResponseUnsupported
+	}
+	length := int(buffer[2])<<24 | int(buffer[3])<<16 | int(buffer[4])<<8 | int(buffer[5])
+	if length > e.max || length < 0 {
+		e.conn.Write([]byte{Version, rejectCode, 0, 0, 0, 0})
+		return buffer[1], nil, ErrTooLarge
+	}
+	if headerSize+length > len(buffer) {
+		grown := make([]byte, headerSize+length)
+		copy(grown, buffer[:n])
+		e.message, buffer = grown, grown
+	}
+	if n < headerSize+length {
+		if _, err := e.conn.Write([]byte{Version, continueCode, 0, 0, 0, 0}); err != nil {
+			return 0, nil, err
+		}
+	}
+	for n < headerSize+length {
+		m, err := e.conn.Read(buffer[n : headerSize+length])
+		if err != nil {
+			return 0, nil, err
+		}
+		n += m
+	}
+	return buffer[1], buffer[headerSize : headerSize+length], nil
+}
+
+// writeMessage sends a message. A message exceeding the MIU is sent in
+// fragments once the peer has answered the first one with a continue.
+func (e *endpoint) writeMessage(code byte, information ...[]byte) error {
+	length := 0
+	for _, info := range information {
+		length += len(info)
+	}
+	message := []byte{Version, code, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}
+	for _, info := range information {
+		message = append(message, info...)
+	}
+	if len(message) <= e.conn.MIU() {
+		_, err := e.conn.Write(message)
+		return err
+	}
+	if _, err := e.conn.Write(message[:e.conn.MIU()]); err != nil {
+		return err
+	}
+	var answer [headerSize]byte
+	if _, err := io.ReadFull(e.conn, answer[:]); err != nil {
+		return err
+	}
+	if answer[1] != RequestContinue && answer[1] != byte(ResponseContinue) {
+		return ResponseReject
+	}
+	_, err := e.conn.Write(message[e.conn.MIU():])
+	return err
+}
+
+// Client sends requests to a SNEP server.
+type Client struct {
+	endpoint
+}
+
+// NewClient creates a client using an established connection.
+func NewClient(conn Conn) *Client {
+	return &Client{endpoint{conn: conn, max: DefaultMaxMessageSize}}
+}
+
+// Dial connects to the default SNEP server of the peer.
+func Dial(link *llcp.Link) (*Client, error) {
+	conn, err := link.Connect(ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// SetMaxMessageSize limits the size of the messages accepted from the server.
+func (c *Client) SetMaxMessageSize(size int) {
+	c.max = size
+}
+
+// Put pushes a NDEF message to the server.
+func (c *Client) Put(ndef []byte) error {
+	if err := c.writeMessage(RequestPut, ndef); err != nil {
+		return err
+	}
+	_, _, err := c.response()
+	return err
+}
+
+// Get requests a NDEF message from the server. The returned message is only
+// valid until the next request.
+func (c *Client) Get(request []byte) ([]byte, error) {
+	acceptable := uint32(c.max)
+	length := []byte{byte(acceptable >> 24), byte(acceptable >> 16), byte(acceptable >> 8), byte(acceptable)}
+	if err := c.writeMessage(RequestGet, length, request); err != nil {
+		return nil, err
+	}
+	_, ndef, err := c.response()
+	return ndef, err
+}
+
+func (c *Client) response() (byte, []byte, error) {
+	code, information, err := c.readMessage(RequestContinue, RequestReject)
+	if err != nil {
+		return code, nil, err
+	}
+	if code != byte(ResponseSuccess) {
+		return code, nil, ResponseError(code)
+	}
+	return code, information, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	if closer, ok := c.conn.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ServeConn answers the requests received on the connection until the client
+// disconnects.
+func ServeConn(conn Conn, handler Handler, maxMessageSize int) error {
+	e := endpoint{conn: conn, max: maxMessageSize}
+	for {
+		code, information, err := e.readMessage(byte(ResponseContinue), byte(ResponseReject))
+		if err == io.EOF {
+			return nil
+		}
+		var response ResponseError
+		switch {
+		case errors.As(err, &response):
+			// the version is not supported
+		case err == ErrTooLarge:
+			// rejected while reading
+			continue
+		case err != nil:
+			return err
+		}
+		if err == nil {
+			response, information = serve(handler, code, information)
+		}
+		if err := e.writeMessage(byte(response), information); err != nil {
+			return err
+		}
+	}
+}
+
+func serve(handler Handler, code byte, information []byte) (ResponseError, []byte) {
+	var err error
+	switch code {
+	case RequestPut:
+		err = handler.Put(information)
+		information = nil
+	case RequestGet:
+		if len(information) < 4 {
+			return ResponseBadRequest, nil
+		}
+		acceptable := uint32(information[0])<<24 | uint32(information[1])<<16 | uint32(information[2])<<8 | uint32(information[3])
+		information, err = handler.Get(information[4:], acceptable)
+		if err == nil && uint32(len(information)) > acceptable {
+			err = ResponseExcessData
+		}
+	default:
+		err = ResponseNotImplemented
+	}
+	if err != nil {
+		var response ResponseError
+		if !errors.As(err, &response) {
+			response = ResponseBadRequest
+		}
+		return response, nil
+	}
+	return ResponseSuccess, information
+}
+
+// ListenAndServe offers the default SNEP server on the link and serves one
+// client after the other until the link is deactivated.
+func ListenAndServe(link *llcp.Link, handler Handler) error {
+	if err := link.Listen(llcp.SAPSNEP, ServiceName); err != nil {
+		return err
+	}
+	for {
+		conn, err := link.Accept(llcp.SAPSNEP)
+		if err != nil {
+			return err
+		}
+		// a failing client does not end the server, a deactivated link
+		// fails Accept
+		if err := ServeConn(conn, handler, DefaultMaxMessageSize); err != nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	"github.com/graugans/tinygo-examples/drivers/ndef"
+	"github.com/graugans/tinygo-examples/drivers/pn532"
+)
+
+// The link handed out to every phone tapping the reader
+const url = "https://tinygo.org"
+
+func main() {
+	const delay = 3
+	for i := 0; i <= delay; i++ {
+		time.Sleep(time.Second) // allow to attach the monitor
+		println("Sleeping...")
+	}
+	err := machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: 400 * machine.KHz,
+		SDA:       0,
+		SCL:       1,
+	})
+	if err != nil {
+		println("Error I2C set-up", err.Error())
+	}
+
+	nfc := pn532.NewI2C(machine.I2C0)
+	if err := nfc.Configure(); err != nil {
+		println("Error Configure: ", err.Error())
+		return
+	}
+	// Enable/Disbale the debug output
+	nfc.Debug(false)
+
+	tag, err := pn532.NewType4TagMessage(&nfc, ndef.NewMessage(ndef.NewURIRecord(url)))
+	if err != nil {
+		println("Error:", err.Error())
+		return
+	}
+	println("-------------------------------------------------------------")
+	println("Waiting for phones to tap the reader .....")
+	for {
+		if err := tag.Serve(0); err != nil {
+			println("Error serving the tag:", err.Error())
+			time.Sleep(time.Second)
+			continue
+		}
+		println("Served " + url)
+	}
+}
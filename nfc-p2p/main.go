@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/hex"
+	"machine"
+	"time"
+
+	"github.com/graugans/tinygo-examples/drivers/pn532"
+)
+
+// One station has to act as initiator, flash the other one with false
+const initiator = true
+
+// The configuration blob handed over to the peer
+var config = []byte("station=1;channel=7;interval=30")
+
+func main() {
+	const delay = 3
+	for i := 0; i <= delay; i++ {
+		time.Sleep(time.Second) // allow to attach the monitor
+		println("Sleeping...")
+	}
+	err := machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: 400 * machine.KHz,
+		SDA:       0,
+		SCL:       1,
+	})
+	if err != nil {
+		println("Error I2C set-up", err.Error())
+	}
+
+	nfc := pn532.NewI2C(machine.I2C0)
+	if err := nfc.Configure(); err != nil {
+		println("Error Configure: ", err.Error())
+		return
+	}
+	// Enable/Disbale the debug output
+	nfc.Debug(false)
+
+	buffer := make([]byte, 256)
+	for {
+		time.Sleep(time.Second)
+		if initiator {
+			runInitiator(&nfc, buffer)
+		} else {
+			runTarget(&nfc, buffer)
+		}
+	}
+}
+
+func runInitiator(nfc *pn532.Device, buffer []byte) {
+	target, err := nfc.InJumpForDEP(&pn532.DEPConfig{
+		Active:   false,
+		BaudRate: pn532.DEPBaudRate106,
+	}, time.Second)
+	if err != nil {
+		// no peer in range
+		return
+	}
+	println("-------------------------------------------------------------")
+	println("Found a DEP target:", hex.EncodeToString(target.NFCID3[:]))
+	n, err := nfc.DEPExchange(target.Tg, config, buffer, time.Second)
+	if err != nil {
+		println("Exchange error:", err.Error())
+		return
+	}
+	println("Received:", string(buffer[:n]))
+}
+
+func runTarget(nfc *pn532.Device, buffer []byte) {
+	_, err := nfc.TgInitAsTarget(&pn532.TargetConfig{
+		Mode:    pn532.TargetModeDEPOnly,
+		SensRes: [2]byte{0x04, 0x00},
+		NFCID1:  [3]byte{0x12, 0x34, 0x56},
+		SelRes:  0x40, // NFC-DEP compliant
+		FeliCa: [18]byte{
+			0x01, 0xFE, 0xA2, 0xA3, 0xA4, 0xA5, 0xA6, 0xA7, // NFCID2t
+			0xC0, 0xC1, 0xC2, 0xC3, 0xC4, 0xC5, 0xC6, 0xC7, // PAD
+			0xFF, 0xFF, // System code
+		},
+		NFCID3: [10]byte{0xAA, 0x99, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11},
+	}, 0)
+	if err != nil {
+		println("Activation error:", err.Error())
+		return
+	}
+	println("-------------------------------------------------------------")
+	n, err := nfc.TgDEPReceive(buffer, time.Second)
+	if err != nil {
+		println("Receive error:", err.Error())
+		return
+	}
+	println("Received:", string(buffer[:n]))
+	if err := nfc.TgDEPSend(config, time.Second); err != nil {
+		println("Send error:", err.Error())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/hex"
+	"machine"
+	"time"
+
+	"github.com/graugans/tinygo-examples/drivers/llcp"
+	"github.com/graugans/tinygo-examples/drivers/pn532"
+	"github.com/graugans/tinygo-examples/drivers/snep"
+)
+
+// printer prints every NDEF message pushed by a phone
+type printer struct{}
+
+func (p printer) Put(ndef []byte) error {
+	println("Received NDEF message:")
+	print(hex.Dump(ndef))
+	return nil
+}
+
+func (p printer) Get(request []byte, acceptable uint32) ([]byte, error) {
+	return nil, snep.ResponseNotFound
+}
+
+func main() {
+	const delay = 3
+	for i := 0; i <= delay; i++ {
+		time.Sleep(time.Second) // allow to attach the monitor
+		println("Sleeping...")
+	}
+	err := machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: 400 * machine.KHz,
+		SDA:       0,
+		SCL:       1,
+	})
+	if err != nil {
+		println("Error I2C set-up", err.Error())
+	}
+
+	nfc := pn532.NewI2C(machine.I2C0)
+	if err := nfc.Configure(); err != nil {
+		println("Error Configure: ", err.Error())
+		return
+	}
+	// Enable/Disbale the debug output
+	nfc.Debug(false)
+
+	params := llcp.DefaultParameters()
+	params.WKS |= 1 << llcp.SAPSNEP
+	for {
+		time.Sleep(time.Second)
+		target, err := nfc.InJumpForDEP(&pn532.DEPConfig{
+			BaudRate:     pn532.DEPBaudRate106,
+			GeneralBytes: llcp.GeneralBytes(&params),
+		}, time.Second)
+		if err != nil {
+			// no phone in range
+			continue
+		}
+		println("-------------------------------------------------------------")
+		dep := pn532.NewDEPInitiatorLink(&nfc, target.Tg, time.Second)
+		link, err := llcp.NewLink(&dep, true, params, target.GeneralBytes)
+		if err != nil {
+			println("LLCP activation error:", err.Error())
+			continue
+		}
+		if err := snep.ListenAndServe(link, printer{}); err != nil {
+			println("Link closed:", err.Error())
+		}
+	}
+}
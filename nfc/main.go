@@ -44,61 +44,44 @@ func main() {
 	println("-------------------------------------------------------------")
 	for {
 		time.Sleep(time.Second)
-		uid, err := nfc.ReadPassiveTargetID(pn532.MIFARE_ISO14443A, 0)
+		target, err := nfc.ReadPassiveTarget(pn532.MIFARE_ISO14443A, 0)
 		if err != nil {
 
 			println(err)
 			continue
 		}
+		uid := target.UID
 		println("-------------------------------------------------------------")
 		println("Found an ISO14443A card")
 		println("  UID Length: " + strconv.Itoa(len(uid)))
 		println("  UID Value:", hex.EncodeToString(uid))
 		println("-------------------------------------------------------------")
-		if len(uid) == 4 { // We assume that this is a Mifare Classic card ...
-			printMifareClasicUID(uid)
+		geometry, isClassic := pn532.MifareClassicGeometryFromSAK(target.SelRes)
+		if isClassic {
+			if len(uid) == 4 {
+				printMifareClasicUID(uid)
+			}
 			mifare := pn532.NewMifareClasic(&nfc)
-			// Now we try to go through all 16 sectors (each having 4 blocks)
-			// authenticating each sector, and then dumping the blocks
-			authenticated := false
+			mifare.SetGeometry(geometry)
+			// Now we try to go through all sectors authenticating each
+			// sector, and then dumping the blocks
 			println("------------------------ Dumping the card content -------------------------")
-			for currentblock := 0; currentblock < 64; currentblock++ {
-				if mifare.IsFirstBlock(uint32(currentblock)) {
-					authenticated = false
-				}
-				if !authenticated {
-					// Starting of a new sector ... try to to authenticate
-					println("------------------------ Sector " + strconv.Itoa(currentblock/4) + " -------------------------")
-					var err error
-					if currentblock == 0 {
-						err = mifare.AuthenticateBlock(uid, uint32(currentblock), pn532.MifareClassicKeyA)
-					} else {
-						err = mifare.AuthenticateBlock(uid, uint32(currentblock), pn532.MifareClassicKeyB)
-					}
-					if err != nil {
-						println("Authentication error: ", err)
-						continue
-					}
-					authenticated = true
-				}
-				if !authenticated {
-					// If we're still not authenticated just skip the block
-					println("Unable to authenticate block: ", currentblock)
+			for sector := uint8(0); sector < geometry.Sectors; sector++ {
+				println("------------------------ Sector " + strconv.Itoa(int(sector)) + " -------------------------")
+				content, err := mifare.ReadSector(uid, sector)
+				if err != nil {
+					println("Unable to read sector:", sector, err.Error())
 					continue
 				}
-				// Authenticated ... we should be able to read the block now
-				// Dump the data into the 'data' slice
-				data, err := mifare.ReadDataBlock(uint8(currentblock))
+				for _, block := range content.Blocks {
+					print(hex.Dump(block[:]))
+				}
+				trailer, err := content.Trailer.Build()
 				if err != nil {
-					// Oops ... something happened
-					println("Unable to read this block:", currentblock)
+					println("Invalid sector trailer:", err.Error())
+					continue
 				}
-				print(hex.Dump(data))
-			}
-
-			if err := mifare.AuthenticateBlock(uid, 4, pn532.MifareClassicKeyA); err != nil {
-				println(err)
-				continue
+				print(hex.Dump(trailer[:]))
 			}
 		}
 		println("-------------------------------------------------------------")